@@ -1640,7 +1640,6 @@ function _Json_addEntry(func)
 	});
 }
 
-var _Json_encodeNull = _Json_wrap(null);
 
 
 
@@ -5761,9 +5760,9 @@ var $author$project$Main$NotFound = function (a) {
 var $author$project$Main$Redirect = function (a) {
 	return {$: 'Redirect', a: a};
 };
-var $author$project$Page$Admin$Model = F6(
-	function (session, bieterList, formPassword, fetchErrorMsg, setStateErrorMsg, resetOffenErrorMsg) {
-		return {bieterList: bieterList, fetchErrorMsg: fetchErrorMsg, formPassword: formPassword, resetOffenErrorMsg: resetOffenErrorMsg, session: session, setStateErrorMsg: setStateErrorMsg};
+var $author$project$Page$Admin$Model = F7(
+	function (session, bieterList, formName, formPassword, fetchErrorMsg, setStateErrorMsg, resetOffenErrorMsg) {
+		return {bieterList: bieterList, fetchErrorMsg: fetchErrorMsg, formName: formName, formPassword: formPassword, resetOffenErrorMsg: resetOffenErrorMsg, session: session, setStateErrorMsg: setStateErrorMsg};
 	});
 var $author$project$Page$Admin$ReceivedBieter = function (a) {
 	return {$: 'ReceivedBieter', a: a};
@@ -5780,7 +5779,9 @@ var $author$project$Bieter$Bieter = function (id) {
 									return function (iban) {
 										return function (abbuchung) {
 											return function (offer) {
-												return {abbuchung: abbuchung, adresse: adresse, iban: iban, id: id, kontoinhaber: kontoinhaber, mail: mail, mitglied: mitglied, name: name, offer: offer, teilpartner: teilpartner, teilpartnerMail: teilpartnerMail, verteilstelle: verteilstelle};
+												return function (token) {
+													return {abbuchung: abbuchung, adresse: adresse, iban: iban, id: id, kontoinhaber: kontoinhaber, mail: mail, mitglied: mitglied, name: name, offer: offer, teilpartner: teilpartner, teilpartnerMail: teilpartnerMail, token: token, verteilstelle: verteilstelle};
+												};
 											};
 										};
 									};
@@ -5894,100 +5895,86 @@ var $NoRedInk$elm_json_decode_pipeline$Json$Decode$Pipeline$required = F3(
 			A2($elm$json$Json$Decode$field, key, valDecoder),
 			decoder);
 	});
-var $author$project$Bieter$Schwenningen = {$: 'Schwenningen'};
-var $author$project$Bieter$Ueberauchen = {$: 'Ueberauchen'};
-var $author$project$Bieter$Villingen = {$: 'Villingen'};
-var $author$project$Bieter$fromVerteilID = function (n) {
-	switch (n) {
-		case 0:
-			return $elm$json$Json$Decode$succeed($elm$core$Maybe$Nothing);
-		case 1:
-			return $elm$json$Json$Decode$succeed(
-				$elm$core$Maybe$Just($author$project$Bieter$Villingen));
-		case 2:
-			return $elm$json$Json$Decode$succeed(
-				$elm$core$Maybe$Just($author$project$Bieter$Schwenningen));
-		case 3:
-			return $elm$json$Json$Decode$succeed(
-				$elm$core$Maybe$Just($author$project$Bieter$Ueberauchen));
-		default:
-			return $elm$json$Json$Decode$fail(
-				'Unbekannte Verteilstelle ' + $elm$core$String$fromInt(n));
-	}
-};
-var $author$project$Bieter$verteilDecoder = A2($elm$json$Json$Decode$andThen, $author$project$Bieter$fromVerteilID, $elm$json$Json$Decode$int);
 var $author$project$Bieter$bieterDecoder = A4(
 	$NoRedInk$elm_json_decode_pipeline$Json$Decode$Pipeline$optional,
-	'offer',
-	$author$project$Offer$decoder,
-	$author$project$Offer$NoOffer,
+	'token',
+	$elm$json$Json$Decode$string,
+	'',
 	A4(
-		$NoRedInk$elm_json_decode_pipeline$Json$Decode$Pipeline$optionalAt,
-		_List_fromArray(
-			['payload', 'abbuchung']),
-		$author$project$Bieter$abbuchungDecoder,
-		$author$project$Bieter$Monatlich,
+		$NoRedInk$elm_json_decode_pipeline$Json$Decode$Pipeline$optional,
+		'offer',
+		$author$project$Offer$decoder,
+		$author$project$Offer$NoOffer,
 		A4(
 			$NoRedInk$elm_json_decode_pipeline$Json$Decode$Pipeline$optionalAt,
 			_List_fromArray(
-				['payload', 'iban']),
-			$elm$json$Json$Decode$string,
-			'',
+				['payload', 'abbuchung']),
+			$author$project$Bieter$abbuchungDecoder,
+			$author$project$Bieter$Monatlich,
 			A4(
 				$NoRedInk$elm_json_decode_pipeline$Json$Decode$Pipeline$optionalAt,
 				_List_fromArray(
-					['payload', 'adresse']),
+					['payload', 'iban']),
 				$elm$json$Json$Decode$string,
 				'',
 				A4(
 					$NoRedInk$elm_json_decode_pipeline$Json$Decode$Pipeline$optionalAt,
 					_List_fromArray(
-						['payload', 'mitglied']),
+						['payload', 'adresse']),
 					$elm$json$Json$Decode$string,
 					'',
 					A4(
 						$NoRedInk$elm_json_decode_pipeline$Json$Decode$Pipeline$optionalAt,
 						_List_fromArray(
-							['payload', 'kontoinhaber']),
+							['payload', 'mitglied']),
 						$elm$json$Json$Decode$string,
 						'',
 						A4(
 							$NoRedInk$elm_json_decode_pipeline$Json$Decode$Pipeline$optionalAt,
 							_List_fromArray(
-								['payload', 'verteilstelle']),
-							$author$project$Bieter$verteilDecoder,
-							$elm$core$Maybe$Nothing,
+								['payload', 'kontoinhaber']),
+							$elm$json$Json$Decode$string,
+							'',
 							A4(
 								$NoRedInk$elm_json_decode_pipeline$Json$Decode$Pipeline$optionalAt,
 								_List_fromArray(
-									['payload', 'teilpartnerMail']),
-								$elm$json$Json$Decode$string,
-								'',
+									['payload', 'verteilstelle']),
+								$elm$json$Json$Decode$int,
+								0,
 								A4(
 									$NoRedInk$elm_json_decode_pipeline$Json$Decode$Pipeline$optionalAt,
 									_List_fromArray(
-										['payload', 'mail']),
+										['payload', 'teilpartnerMail']),
 									$elm$json$Json$Decode$string,
 									'',
 									A4(
 										$NoRedInk$elm_json_decode_pipeline$Json$Decode$Pipeline$optionalAt,
 										_List_fromArray(
-											['payload', 'teilpartner']),
+											['payload', 'mail']),
 										$elm$json$Json$Decode$string,
 										'',
 										A4(
 											$NoRedInk$elm_json_decode_pipeline$Json$Decode$Pipeline$optionalAt,
 											_List_fromArray(
-												['payload', 'name']),
+												['payload', 'teilpartner']),
 											$elm$json$Json$Decode$string,
 											'',
-											A3(
-												$NoRedInk$elm_json_decode_pipeline$Json$Decode$Pipeline$required,
-												'id',
-												$author$project$Bieter$idDecoder,
-												$elm$json$Json$Decode$succeed($author$project$Bieter$Bieter)))))))))))));
+											A4(
+												$NoRedInk$elm_json_decode_pipeline$Json$Decode$Pipeline$optionalAt,
+												_List_fromArray(
+													['payload', 'name']),
+												$elm$json$Json$Decode$string,
+												'',
+												A3(
+													$NoRedInk$elm_json_decode_pipeline$Json$Decode$Pipeline$required,
+													'id',
+													$author$project$Bieter$idDecoder,
+													$elm$json$Json$Decode$succeed($author$project$Bieter$Bieter))))))))))))));
 var $elm$json$Json$Decode$list = _Json_decodeList;
 var $author$project$Bieter$bieterListDecoder = $elm$json$Json$Decode$list($author$project$Bieter$bieterDecoder);
+var $author$project$Bieter$key = function (bieter) {
+	return (bieter.token === '') ? bieter.id : $author$project$Bieter$ID(bieter.token);
+};
 var $elm$http$Http$BadStatus_ = F2(
 	function (a, b) {
 		return {$: 'BadStatus_', a: a, b: b};
@@ -6611,10 +6598,10 @@ var $elm$http$Http$header = $elm$http$Http$Header;
 var $author$project$Session$headers = function (s) {
 	var _v0 = s.admin;
 	if (_v0.$ === 'IsAdmin') {
-		var pw = _v0.a;
+		var token = _v0.a;
 		return _List_fromArray(
 			[
-				A2($elm$http$Http$header, 'auth', pw)
+				A2($elm$http$Http$header, 'auth', token)
 			]);
 	} else {
 		return _List_Nil;
@@ -6812,7 +6799,7 @@ var $elm$core$Platform$Cmd$none = $elm$core$Platform$Cmd$batch(_List_Nil);
 var $author$project$Page$Admin$init = function (session) {
 	var cmd = $author$project$Session$isAdmin(session) ? $author$project$Page$Admin$fetchBieterList(session) : $elm$core$Platform$Cmd$none;
 	return _Utils_Tuple2(
-		A6($author$project$Page$Admin$Model, session, $elm$core$Maybe$Nothing, '', $elm$core$Maybe$Nothing, $elm$core$Maybe$Nothing, $elm$core$Maybe$Nothing),
+		A7($author$project$Page$Admin$Model, session, $elm$core$Maybe$Nothing, '', '', $elm$core$Maybe$Nothing, $elm$core$Maybe$Nothing, $elm$core$Maybe$Nothing),
 		cmd);
 };
 var $author$project$Page$Front$Model = function (session) {
@@ -6826,7 +6813,9 @@ var $author$project$Page$Front$Model = function (session) {
 								return function (draftOffer) {
 									return function (offerValid) {
 										return function (offerErrorMsg) {
-											return {draftBieter: draftBieter, draftOffer: draftOffer, editErrorMsg: editErrorMsg, ibanValid: ibanValid, loginErrorMsg: loginErrorMsg, loginFormBieterName: loginFormBieterName, loginFormBieterNr: loginFormBieterNr, offerErrorMsg: offerErrorMsg, offerValid: offerValid, page: page, session: session};
+											return function (verteilstellen) {
+												return {draftBieter: draftBieter, draftOffer: draftOffer, editErrorMsg: editErrorMsg, ibanValid: ibanValid, loginErrorMsg: loginErrorMsg, loginFormBieterName: loginFormBieterName, loginFormBieterNr: loginFormBieterNr, offerErrorMsg: offerErrorMsg, offerValid: offerValid, page: page, session: session, verteilstellen: verteilstellen};
+											};
 										};
 									};
 								};
@@ -6860,7 +6849,8 @@ var $author$project$Session$toBieterID = function (s) {
 	switch (_v0.$) {
 		case 'LoggedIn':
 			var bieter = _v0.a;
-			return $elm$core$Maybe$Just(bieter.id);
+			return $elm$core$Maybe$Just(
+				$author$project$Bieter$key(bieter));
 		case 'Loading':
 			var id = _v0.a;
 			return $elm$core$Maybe$Just(id);
@@ -6887,6 +6877,37 @@ var $author$project$Offer$toInputString = function (maybeOffer) {
 			return $elm$core$String$fromInt(euro) + ('.' + correctCent);
 	}
 };
+var $author$project$Page$Front$ReceivedVerteilstellen = function (a) {
+	return {$: 'ReceivedVerteilstellen', a: a};
+};
+var $author$project$Verteilstelle$Verteilstelle = F3(
+	function (id, name, full) {
+		return {full: full, id: id, name: name};
+	});
+var $elm$json$Json$Decode$bool = _Json_decodeBool;
+var $author$project$Verteilstelle$decoder = A3(
+	$NoRedInk$elm_json_decode_pipeline$Json$Decode$Pipeline$required,
+	'full',
+	$elm$json$Json$Decode$bool,
+	A3(
+		$NoRedInk$elm_json_decode_pipeline$Json$Decode$Pipeline$required,
+		'name',
+		$elm$json$Json$Decode$string,
+		A3(
+			$NoRedInk$elm_json_decode_pipeline$Json$Decode$Pipeline$required,
+			'id',
+			$elm$json$Json$Decode$int,
+			$elm$json$Json$Decode$succeed($author$project$Verteilstelle$Verteilstelle))));
+var $author$project$Verteilstelle$fetch = function (result) {
+	return $elm$http$Http$get(
+		{
+			expect: A2(
+				$elm$http$Http$expectJson,
+				result,
+				$elm$json$Json$Decode$list($author$project$Verteilstelle$decoder)),
+			url: '/api/verteilstelle'
+		});
+};
 var $author$project$Page$Front$init = function (session) {
 	var offer = function () {
 		var _v1 = $author$project$Session$toBieter(session);
@@ -6907,8 +6928,8 @@ var $author$project$Page$Front$init = function (session) {
 		}
 	}();
 	return _Utils_Tuple2(
-		$author$project$Page$Front$Model(session)($author$project$Page$Front$Show)($elm$core$Maybe$Nothing)(bieterID)('')($elm$core$Maybe$Nothing)($elm$core$Maybe$Nothing)(false)(offer)(false)($elm$core$Maybe$Nothing),
-		$elm$core$Platform$Cmd$none);
+		$author$project$Page$Front$Model(session)($author$project$Page$Front$Show)($elm$core$Maybe$Nothing)(bieterID)('')($elm$core$Maybe$Nothing)($elm$core$Maybe$Nothing)(false)(offer)(false)($elm$core$Maybe$Nothing)(_List_Nil),
+		$author$project$Verteilstelle$fetch($author$project$Page$Front$ReceivedVerteilstellen));
 };
 var $author$project$Session$Loading = function (a) {
 	return {$: 'Loading', a: a};
@@ -7382,6 +7403,8 @@ var $author$project$Route$parser = $elm$url$Url$Parser$oneOf(
 var $author$project$Route$fromUrl = function (url) {
 	return A2($elm$url$Url$Parser$parse, $author$project$Route$parser, url);
 };
+var $author$project$State$Closed = {$: 'Closed'};
+var $author$project$State$Contract = {$: 'Contract'};
 var $author$project$State$Loading = {$: 'Loading'};
 var $author$project$State$Offer = {$: 'Offer'};
 var $author$project$State$Registration = {$: 'Registration'};
@@ -7394,6 +7417,10 @@ var $author$project$State$stateDecoder = function (n) {
 			return $author$project$State$Validation;
 		case 3:
 			return $author$project$State$Offer;
+		case 4:
+			return $author$project$State$Closed;
+		case 5:
+			return $author$project$State$Contract;
 		default:
 			return $author$project$State$Unknown;
 	}
@@ -7755,7 +7782,8 @@ var $author$project$Session$loggedIn = F2(
 					viewer: $author$project$Session$LoggedIn(bieter)
 				}),
 			$author$project$Ports$send(
-				$author$project$Ports$StoreBieterID(bieter.id)));
+				$author$project$Ports$StoreBieterID(
+					$author$project$Bieter$key(bieter))));
 	});
 var $elm$browser$Browser$Navigation$pushUrl = _Browser_pushUrl;
 var $author$project$Session$stateChanged = F2(
@@ -7857,13 +7885,13 @@ var $author$project$Session$IsAdmin = function (a) {
 	return {$: 'IsAdmin', a: a};
 };
 var $author$project$Session$withAdmin = F2(
-	function (maybePassword, session) {
-		if (maybePassword.$ === 'Just') {
-			var pw = maybePassword.a;
+	function (maybeToken, session) {
+		if (maybeToken.$ === 'Just') {
+			var token = maybeToken.a;
 			return _Utils_update(
 				session,
 				{
-					admin: $author$project$Session$IsAdmin(pw)
+					admin: $author$project$Session$IsAdmin(token)
 				});
 		} else {
 			return _Utils_update(
@@ -7893,7 +7921,7 @@ var $author$project$Page$Admin$fetchBieterResponse = F2(
 					_Utils_update(
 						model,
 						{
-							fetchErrorMsg: $elm$core$Maybe$Just('Passwort is falsch'),
+							fetchErrorMsg: $elm$core$Maybe$Just('Die Anmeldung ist abgelaufen'),
 							session: A2($author$project$Session$withAdmin, $elm$core$Maybe$Nothing, model.session)
 						}),
 					$elm$core$Platform$Cmd$none) : _Utils_Tuple2(
@@ -7910,12 +7938,14 @@ var $author$project$Page$Admin$fetchBieterResponse = F2(
 			}
 		}
 	});
+var $author$project$State$closed = 'Abgeschlossen';
+var $author$project$State$contract = 'Vertrag';
 var $author$project$State$loading = 'Wird geladen';
 var $author$project$State$offer = 'Bieten';
 var $author$project$State$registration = 'Registrierung';
 var $author$project$State$validation = 'Überprüfung';
 var $author$project$State$fromString = function (state) {
-	return _Utils_eq(state, $author$project$State$loading) ? $author$project$State$Loading : (_Utils_eq(state, $author$project$State$registration) ? $author$project$State$Registration : (_Utils_eq(state, $author$project$State$validation) ? $author$project$State$Validation : (_Utils_eq(state, $author$project$State$offer) ? $author$project$State$Offer : $author$project$State$Unknown)));
+	return _Utils_eq(state, $author$project$State$loading) ? $author$project$State$Loading : (_Utils_eq(state, $author$project$State$registration) ? $author$project$State$Registration : (_Utils_eq(state, $author$project$State$validation) ? $author$project$State$Validation : (_Utils_eq(state, $author$project$State$offer) ? $author$project$State$Offer : (_Utils_eq(state, $author$project$State$closed) ? $author$project$State$Closed : (_Utils_eq(state, $author$project$State$contract) ? $author$project$State$Contract : $author$project$State$Unknown)))));
 };
 var $author$project$Page$Admin$reload = function (model) {
 	var _v0 = A2($author$project$Session$loadState, model.session, $author$project$Page$Admin$SetStateResult);
@@ -7978,6 +8008,10 @@ var $author$project$State$stateEncoder = function (state) {
 				return 2;
 			case 'Offer':
 				return 3;
+			case 'Closed':
+				return 4;
+			case 'Contract':
+				return 5;
 			default:
 				return 0;
 		}
@@ -8004,6 +8038,39 @@ var $author$project$State$setState = F3(
 				url: '/api/state'
 			});
 	});
+var $author$project$Page$Admin$ReceivedLogin = function (a) {
+	return {$: 'ReceivedLogin', a: a};
+};
+var $author$project$Page$Admin$loginEncoder = F2(
+	function (name, password) {
+		return $elm$json$Json$Encode$object(
+			_List_fromArray(
+				[
+					_Utils_Tuple2(
+					'name',
+					$elm$json$Json$Encode$string(name)),
+					_Utils_Tuple2(
+					'password',
+					$elm$json$Json$Encode$string(password))
+				]));
+	});
+var $author$project$Page$Admin$login = F2(
+	function (name, password) {
+		return $elm$http$Http$request(
+			{
+				body: $elm$http$Http$jsonBody(
+					A2($author$project$Page$Admin$loginEncoder, name, password)),
+				expect: A2(
+					$elm$http$Http$expectJson,
+					$author$project$Page$Admin$ReceivedLogin,
+					A2($elm$json$Json$Decode$field, 'token', $elm$json$Json$Decode$string)),
+				headers: _List_Nil,
+				method: 'POST',
+				timeout: $elm$core$Maybe$Nothing,
+				tracker: $elm$core$Maybe$Nothing,
+				url: '/api/login'
+			});
+	});
 var $author$project$Page$Admin$update = F2(
 	function (msg, model) {
 		switch (msg.$) {
@@ -8012,6 +8079,13 @@ var $author$project$Page$Admin$update = F2(
 			case 'ReceivedBieter':
 				var response = msg.a;
 				return A2($author$project$Page$Admin$fetchBieterResponse, model, response);
+			case 'LoginFormSaveName':
+				var name = msg.a;
+				return _Utils_Tuple2(
+					_Utils_update(
+						model,
+						{formName: name}),
+					$elm$core$Platform$Cmd$none);
 			case 'LoginFormSavePassword':
 				var pw = msg.a;
 				return _Utils_Tuple2(
@@ -8020,18 +8094,42 @@ var $author$project$Page$Admin$update = F2(
 						{formPassword: pw}),
 					$elm$core$Platform$Cmd$none);
 			case 'LoginFormSubmit':
-				if (model.formPassword === '') {
-					return _Utils_Tuple2(model, $elm$core$Platform$Cmd$none);
-				} else {
+				return (model.formPassword === '') ? _Utils_Tuple2(model, $elm$core$Platform$Cmd$none) : _Utils_Tuple2(
+					model,
+					A2($author$project$Page$Admin$login, model.formName, model.formPassword));
+			case 'ReceivedLogin':
+				var result = msg.a;
+				if (result.$ === 'Ok') {
+					var token = result.a;
 					var session = A2(
 						$author$project$Session$withAdmin,
-						$elm$core$Maybe$Just(model.formPassword),
+						$elm$core$Maybe$Just(token),
 						model.session);
 					return _Utils_Tuple2(
 						_Utils_update(
 							model,
-							{session: session}),
+							{fetchErrorMsg: $elm$core$Maybe$Nothing, formPassword: '', session: session}),
 						$author$project$Page$Admin$fetchBieterList(session));
+				} else {
+					if ((result.a.$ === 'BadStatus') && (result.a.a === 401)) {
+						return _Utils_Tuple2(
+							_Utils_update(
+								model,
+								{
+									fetchErrorMsg: $elm$core$Maybe$Just('Name oder Passwort ist falsch')
+								}),
+							$elm$core$Platform$Cmd$none);
+					} else {
+						var e = result.a;
+						return _Utils_Tuple2(
+							_Utils_update(
+								model,
+								{
+									fetchErrorMsg: $elm$core$Maybe$Just(
+										$author$project$Page$Admin$buildErrorMessage(e))
+								}),
+							$elm$core$Platform$Cmd$none);
+					}
 				}
 			case 'SetState':
 				var state = msg.a;
@@ -8130,7 +8228,7 @@ var $author$project$Page$Front$buildErrorMessage = function (httpError) {
 		case 'BadStatus':
 			var statusCode = httpError.a;
 			if (statusCode === 404) {
-				return 'Unbekannte Bieternummer';
+				return 'Unbekannter Zugangscode';
 			} else {
 				return 'Request failed with status code: ' + $elm$core$String$fromInt(statusCode);
 			}
@@ -9286,27 +9384,6 @@ var $author$project$Bieter$abbuchungEncoder = function (abbuchung) {
 		return $elm$json$Json$Encode$int(0);
 	}
 };
-var $elm$json$Json$Encode$null = _Json_encodeNull;
-var $author$project$Bieter$verteilEncoder = function (verteiler) {
-	if (verteiler.$ === 'Nothing') {
-		return $elm$json$Json$Encode$null;
-	} else {
-		switch (verteiler.a.$) {
-			case 'AuswahlVerteilstelle':
-				var _v1 = verteiler.a;
-				return $elm$json$Json$Encode$int(0);
-			case 'Villingen':
-				var _v2 = verteiler.a;
-				return $elm$json$Json$Encode$int(1);
-			case 'Schwenningen':
-				var _v3 = verteiler.a;
-				return $elm$json$Json$Encode$int(2);
-			default:
-				var _v4 = verteiler.a;
-				return $elm$json$Json$Encode$int(3);
-		}
-	}
-};
 var $author$project$Bieter$bieterEncoder = function (bieter) {
 	return $elm$json$Json$Encode$object(
 		_List_fromArray(
@@ -9325,7 +9402,7 @@ var $author$project$Bieter$bieterEncoder = function (bieter) {
 				$elm$json$Json$Encode$string(bieter.teilpartnerMail)),
 				_Utils_Tuple2(
 				'verteilstelle',
-				$author$project$Bieter$verteilEncoder(bieter.verteilstelle)),
+				$elm$json$Json$Encode$int(bieter.verteilstelle)),
 				_Utils_Tuple2(
 				'kontoinhaber',
 				$elm$json$Json$Encode$string(bieter.kontoinhaber)),
@@ -9357,24 +9434,10 @@ var $author$project$Page$Front$updateBieter = F2(
 					method: 'PUT',
 					timeout: $elm$core$Maybe$Nothing,
 					tracker: $elm$core$Maybe$Nothing,
-					url: '/api/bieter/' + $author$project$Bieter$idToString(bieter.id)
+					url: '/api/bieter/' + $author$project$Bieter$idToString(
+						$author$project$Bieter$key(bieter))
 				}));
 	});
-var $author$project$Bieter$AuswahlVerteilstelle = {$: 'AuswahlVerteilstelle'};
-var $author$project$Bieter$verteilerFromString = function (s) {
-	switch (s) {
-		case 'Wähle deine Verteilstelle':
-			return $elm$core$Maybe$Just($author$project$Bieter$AuswahlVerteilstelle);
-		case 'Villingen':
-			return $elm$core$Maybe$Just($author$project$Bieter$Villingen);
-		case 'Schwenningen':
-			return $elm$core$Maybe$Just($author$project$Bieter$Schwenningen);
-		case 'Überauchen':
-			return $elm$core$Maybe$Just($author$project$Bieter$Ueberauchen);
-		default:
-			return $elm$core$Maybe$Nothing;
-	}
-};
 var $author$project$Page$Front$updateEditPage = F2(
 	function (model, editMsg) {
 		var _v0 = model.draftBieter;
@@ -9441,7 +9504,10 @@ var $author$project$Page$Front$updateEditPage = F2(
 									_Utils_update(
 										bieter,
 										{
-											verteilstelle: $author$project$Bieter$verteilerFromString(verteiler)
+											verteilstelle: A2(
+												$elm$core$Maybe$withDefault,
+												0,
+												$elm$core$String$toInt(verteiler))
 										}))
 							}),
 						$elm$core$Platform$Cmd$none);
@@ -9573,7 +9639,7 @@ var $author$project$Page$Front$update = F2(
 							draftBieter: $author$project$Session$toBieter(model.session),
 							page: $author$project$Page$Front$Edit
 						}),
-					$elm$core$Platform$Cmd$none);
+					$author$project$Verteilstelle$fetch($author$project$Page$Front$ReceivedVerteilstellen));
 			case 'SaveNumber':
 				var nr = msg.a;
 				return _Utils_Tuple2(
@@ -9674,7 +9740,8 @@ var $author$project$Page$Front$update = F2(
 							$author$project$Offer$send,
 							$author$project$Page$Front$ReceiveOffer,
 							$author$project$Session$headers(model.session),
-							$author$project$Bieter$idToString(bieter.id),
+							$author$project$Bieter$idToString(
+								$author$project$Bieter$key(bieter)),
 							offer));
 				} else {
 					return _Utils_Tuple2(
@@ -9685,7 +9752,7 @@ var $author$project$Page$Front$update = F2(
 							}),
 						$elm$core$Platform$Cmd$none);
 				}
-			default:
+			case 'ReceiveOffer':
 				var result = msg.a;
 				if (result.$ === 'Ok') {
 					var offer = result.a;
@@ -9728,6 +9795,26 @@ var $author$project$Page$Front$update = F2(
 							}),
 						$elm$core$Platform$Cmd$none);
 				}
+			default:
+				var result = msg.a;
+				if (result.$ === 'Ok') {
+					var verteilstellen = result.a;
+					return _Utils_Tuple2(
+						_Utils_update(
+							model,
+							{verteilstellen: verteilstellen}),
+						$elm$core$Platform$Cmd$none);
+				} else {
+					var e = result.a;
+					return _Utils_Tuple2(
+						_Utils_update(
+							model,
+							{
+								editErrorMsg: $elm$core$Maybe$Just(
+									$author$project$Page$Front$buildErrorMessage(e))
+							}),
+						$elm$core$Platform$Cmd$none);
+				}
 		}
 	});
 var $author$project$Page$Admin$updateSession = F2(
@@ -9775,7 +9862,11 @@ var $author$project$Main$update = F2(
 							return _Utils_Tuple2(session, $elm$core$Platform$Cmd$none);
 						} else {
 							var bieter = _v3.a;
-							return A3($author$project$Session$loadBieter, session, $author$project$Main$ReceivedBieter, bieter.id);
+							return A3(
+								$author$project$Session$loadBieter,
+								session,
+								$author$project$Main$ReceivedBieter,
+								$author$project$Bieter$key(bieter));
 						}
 					}();
 					var cmdLoadBieter = _v2.b;
@@ -10319,8 +10410,12 @@ var $author$project$State$toString = function (state) {
 			return $author$project$State$registration;
 		case 'Validation':
 			return $author$project$State$validation;
-		default:
+		case 'Offer':
 			return $author$project$State$offer;
+		case 'Closed':
+			return $author$project$State$closed;
+		default:
+			return $author$project$State$contract;
 	}
 };
 var $author$project$Page$Admin$viewStatusSelect = function (model) {
@@ -10407,6 +10502,30 @@ var $author$project$Page$Admin$viewStatusSelect = function (model) {
 							[
 								$elm$html$Html$text(
 								$author$project$State$toString($author$project$State$Offer))
+							])),
+						A2(
+						$elm$html$Html$option,
+						_List_fromArray(
+							[
+								$elm$html$Html$Attributes$selected(
+								_Utils_eq(state, $author$project$State$Closed))
+							]),
+						_List_fromArray(
+							[
+								$elm$html$Html$text(
+								$author$project$State$toString($author$project$State$Closed))
+							])),
+						A2(
+						$elm$html$Html$option,
+						_List_fromArray(
+							[
+								$elm$html$Html$Attributes$selected(
+								_Utils_eq(state, $author$project$State$Contract))
+							]),
+						_List_fromArray(
+							[
+								$elm$html$Html$text(
+								$author$project$State$toString($author$project$State$Contract))
 							]))
 					]))
 			]));
@@ -10476,6 +10595,9 @@ var $author$project$Page$Admin$viewAdmin = function (model) {
 var $author$project$Page$Admin$LoginFormSavePassword = function (a) {
 	return {$: 'LoginFormSavePassword', a: a};
 };
+var $author$project$Page$Admin$LoginFormSaveName = function (a) {
+	return {$: 'LoginFormSaveName', a: a};
+};
 var $author$project$Page$Admin$LoginFormSubmit = {$: 'LoginFormSubmit'};
 var $elm$html$Html$Attributes$autofocus = $elm$html$Html$Attributes$boolProperty('autofocus');
 var $elm$html$Html$form = _VirtualDom_node('form');
@@ -10526,6 +10648,17 @@ var $author$project$Page$Admin$viewLogin = function (model) {
 					]),
 				_List_fromArray(
 					[
+						$elm$html$Html$text('Name (leer für admin)'),
+						A2(
+						$elm$html$Html$input,
+						_List_fromArray(
+							[
+								$elm$html$Html$Attributes$type_('text'),
+								$elm$html$Html$Attributes$value(model.formName),
+								$elm$html$Html$Events$onInput($author$project$Page$Admin$LoginFormSaveName),
+								$elm$html$Html$Attributes$autofocus(true)
+							]),
+						_List_Nil),
 						$elm$html$Html$text('Passwort'),
 						A2(
 						$elm$html$Html$input,
@@ -10533,8 +10666,7 @@ var $author$project$Page$Admin$viewLogin = function (model) {
 							[
 								$elm$html$Html$Attributes$type_('password'),
 								$elm$html$Html$Attributes$value(model.formPassword),
-								$elm$html$Html$Events$onInput($author$project$Page$Admin$LoginFormSavePassword),
-								$elm$html$Html$Attributes$autofocus(true)
+								$elm$html$Html$Events$onInput($author$project$Page$Admin$LoginFormSavePassword)
 							]),
 						_List_Nil),
 						A2(
@@ -10589,26 +10721,6 @@ var $author$project$Permission$hasPerm = F2(
 	});
 var $elm$virtual_dom$VirtualDom$style = _VirtualDom_style;
 var $elm$html$Html$Attributes$style = $elm$virtual_dom$VirtualDom$style;
-var $author$project$Bieter$verteilerToString = function (maybeVerteiler) {
-	if (maybeVerteiler.$ === 'Nothing') {
-		return 'Unbekannte';
-	} else {
-		switch (maybeVerteiler.a.$) {
-			case 'AuswahlVerteilstelle':
-				var _v1 = maybeVerteiler.a;
-				return 'Wähle deine Verteilstelle';
-			case 'Villingen':
-				var _v2 = maybeVerteiler.a;
-				return 'Villingen';
-			case 'Schwenningen':
-				var _v3 = maybeVerteiler.a;
-				return 'Schwenningen';
-			default:
-				var _v4 = maybeVerteiler.a;
-				return 'Überauchen';
-		}
-	}
-};
 var $author$project$Permission$CanOffer = {$: 'CanOffer'};
 var $author$project$Page$Front$SaveDraftOffer = function (a) {
 	return {$: 'SaveDraftOffer', a: a};
@@ -14604,8 +14716,23 @@ var $author$project$Page$Front$viewQRCode = F2(
 						$pablohirafuji$elm_qrcode$QRCode$fromString(message)))
 				]));
 	});
-var $author$project$Page$Front$viewBieter = F6(
-	function (session, baseURL, bieter, draftOffer, error, offerValid) {
+var $author$project$Verteilstelle$name = F2(
+	function (verteilstellen, id) {
+		var _v0 = A2(
+			$elm$core$List$filter,
+			function (v) {
+				return _Utils_eq(v.id, id);
+			},
+			verteilstellen);
+		if (_v0.b) {
+			var verteilstelle = _v0.a;
+			return verteilstelle.name;
+		} else {
+			return 'Unbekannte';
+		}
+	});
+var $author$project$Page$Front$viewBieter = F7(
+	function (session, baseURL, verteilstellen, bieter, draftOffer, error, offerValid) {
 		var maybeEditButton = A2($author$project$Permission$hasPerm, $author$project$Permission$CanEdit, session) ? A2(
 			$elm$html$Html$div,
 			_List_Nil,
@@ -14649,7 +14776,17 @@ var $author$project$Page$Front$viewBieter = F6(
 										$elm$html$Html$text(
 										$author$project$Bieter$idToString(bieter.id))
 									])),
-								$elm$html$Html$text('. Merke sie dir gut. Du brauchst sie für die nächste Anmeldung')
+								$elm$html$Html$text('. Dein Zugangscode ist '),
+								A2(
+								$elm$html$Html$strong,
+								_List_Nil,
+								_List_fromArray(
+									[
+										$elm$html$Html$text(
+										$author$project$Bieter$idToString(
+											$author$project$Bieter$key(bieter)))
+									])),
+								$elm$html$Html$text('. Merke ihn dir gut. Du brauchst ihn für die nächste Anmeldung')
 							])),
 						A2(
 						$elm$html$Html$div,
@@ -14670,7 +14807,7 @@ var $author$project$Page$Front$viewBieter = F6(
 						_List_fromArray(
 							[
 								$elm$html$Html$text(
-								'Verteilstelle: ' + $author$project$Bieter$verteilerToString(bieter.verteilstelle))
+								'Verteilstelle: ' + A2($author$project$Verteilstelle$name, verteilstellen, bieter.verteilstelle))
 							])),
 						A2(
 						$elm$html$Html$div,
@@ -14749,14 +14886,18 @@ var $author$project$Page$Front$viewBieter = F6(
 						_List_fromArray(
 							[
 								$elm$html$Html$Attributes$href(
-								'/api/bieter/' + ($author$project$Bieter$idToString(bieter.id) + '/pdf'))
+								'/api/bieter/' + ($author$project$Bieter$idToString(
+									$author$project$Bieter$key(bieter)) + '/pdf'))
 							]),
 						_List_fromArray(
 							[
 								$elm$html$Html$text('Bietervertrag (PDF)')
 							])),
 						A5($author$project$Page$Front$viewOffer, session, bieter, draftOffer, error, offerValid),
-						A2($author$project$Page$Front$viewQRCode, baseURL, bieter.id)
+						A2(
+						$author$project$Page$Front$viewQRCode,
+						baseURL,
+						$author$project$Bieter$key(bieter))
 					])),
 			title: 'Bieter'
 		};
@@ -14793,6 +14934,24 @@ var $author$project$Page$Front$SaveVerteilstelle = function (a) {
 	return {$: 'SaveVerteilstelle', a: a};
 };
 var $author$project$Page$Front$Submit = {$: 'Submit'};
+var $author$project$Page$Front$viewVerteilstelleOption = F2(
+	function (chosen, verteilstelle) {
+		var label = verteilstelle.full ? (verteilstelle.name + ' (voll)') : verteilstelle.name;
+		return A2(
+			$elm$html$Html$option,
+			_List_fromArray(
+				[
+					$elm$html$Html$Attributes$value(
+					$elm$core$String$fromInt(verteilstelle.id)),
+					$elm$html$Html$Attributes$selected(
+					_Utils_eq(verteilstelle.id, chosen)),
+					$elm$html$Html$Attributes$disabled(verteilstelle.full && (!_Utils_eq(verteilstelle.id, chosen)))
+				]),
+			_List_fromArray(
+				[
+					$elm$html$Html$text(label)
+				]));
+	});
 var $author$project$Page$Front$viewEdit = function (model) {
 	var _v0 = model.draftBieter;
 	if (_v0.$ === 'Nothing') {
@@ -14901,61 +15060,23 @@ var $author$project$Page$Front$viewEdit = function (model) {
 											[
 												$elm$html$Html$Events$onInput($author$project$Page$Front$SaveVerteilstelle)
 											]),
-										_List_fromArray(
-											[
-												A2(
+										A2(
+											$elm$core$List$cons,
+											A2(
 												$elm$html$Html$option,
 												_List_fromArray(
 													[
-														$elm$html$Html$Attributes$selected(
-														_Utils_eq(
-															bieter.verteilstelle,
-															$elm$core$Maybe$Just($author$project$Bieter$AuswahlVerteilstelle)))
+														$elm$html$Html$Attributes$value('0'),
+														$elm$html$Html$Attributes$selected(!bieter.verteilstelle)
 													]),
 												_List_fromArray(
 													[
 														$elm$html$Html$text('Wähle deine Verteilstelle')
 													])),
-												A2(
-												$elm$html$Html$option,
-												_List_fromArray(
-													[
-														$elm$html$Html$Attributes$selected(
-														_Utils_eq(
-															bieter.verteilstelle,
-															$elm$core$Maybe$Just($author$project$Bieter$Villingen)))
-													]),
-												_List_fromArray(
-													[
-														$elm$html$Html$text('Villingen')
-													])),
-												A2(
-												$elm$html$Html$option,
-												_List_fromArray(
-													[
-														$elm$html$Html$Attributes$selected(
-														_Utils_eq(
-															bieter.verteilstelle,
-															$elm$core$Maybe$Just($author$project$Bieter$Schwenningen)))
-													]),
-												_List_fromArray(
-													[
-														$elm$html$Html$text('Schwenningen')
-													])),
-												A2(
-												$elm$html$Html$option,
-												_List_fromArray(
-													[
-														$elm$html$Html$Attributes$selected(
-														_Utils_eq(
-															bieter.verteilstelle,
-															$elm$core$Maybe$Just($author$project$Bieter$Ueberauchen)))
-													]),
-												_List_fromArray(
-													[
-														$elm$html$Html$text('Überauchen')
-													]))
-											]))
+											A2(
+												$elm$core$List$map,
+												$author$project$Page$Front$viewVerteilstelleOption(bieter.verteilstelle),
+												model.verteilstellen)))
 									])),
 								A2(
 								$elm$html$Html$div,
@@ -15186,7 +15307,7 @@ var $author$project$Page$Front$viewLogin = function (loginData) {
 					_List_Nil,
 					_List_fromArray(
 						[
-							$elm$html$Html$text('Mit Bieternummer anmelden')
+							$elm$html$Html$text('Mit Zugangscode anmelden')
 						])),
 					$author$project$Page$Front$maybeError(loginData.loginErrorMsg),
 					A2(
@@ -15202,7 +15323,7 @@ var $author$project$Page$Front$viewLogin = function (loginData) {
 							_List_Nil,
 							_List_fromArray(
 								[
-									$elm$html$Html$text('Bieternummer'),
+									$elm$html$Html$text('Zugangscode'),
 									A2(
 									$elm$html$Html$input,
 									_List_fromArray(
@@ -15244,7 +15365,7 @@ var $author$project$Page$Front$view = function (model) {
 		var bieter = maybeBieter.a;
 		var _v1 = model.page;
 		if (_v1.$ === 'Show') {
-			return A6($author$project$Page$Front$viewBieter, model.session, model.session.baseURL, bieter, model.draftOffer, model.offerErrorMsg, model.offerValid);
+			return A7($author$project$Page$Front$viewBieter, model.session, model.session.baseURL, model.verteilstellen, bieter, model.draftOffer, model.offerErrorMsg, model.offerValid);
 		} else {
 			var _v2 = $author$project$Page$Front$viewEdit(model);
 			var title = _v2.title;
@@ -1,4 +1,4 @@
-module Bieter exposing (Abbuchung(..), Bieter, ID, Verteilstelle(..), abbuchungFromString, abbuchungToString, bieterDecoder, bieterEncoder, bieterListDecoder, fetch, idDecoder, idFromString, idToString, urlParser, verteilerFromString, verteilerToString)
+module Bieter exposing (Abbuchung(..), Bieter, ID, abbuchungFromString, abbuchungToString, bieterDecoder, bieterEncoder, bieterListDecoder, fetch, idDecoder, idFromString, idToString, key, urlParser)
 
 import Http
 import Json.Decode as Decode exposing (Decoder, string)
@@ -15,13 +15,14 @@ type alias Bieter =
     , teilpartner : String
     , mail : String
     , teilpartnerMail : String
-    , verteilstelle : Maybe Verteilstelle
+    , verteilstelle : Int
     , kontoinhaber : String
     , mitglied : String
     , adresse : String
     , iban : String
     , abbuchung : Abbuchung
     , offer : Offer.Offer
+    , token : String
     }
 
 
@@ -33,13 +34,14 @@ bieterDecoder =
         |> optionalAt [ "payload", "teilpartner" ] Decode.string ""
         |> optionalAt [ "payload", "mail" ] Decode.string ""
         |> optionalAt [ "payload", "teilpartnerMail" ] Decode.string ""
-        |> optionalAt [ "payload", "verteilstelle" ] verteilDecoder Nothing
+        |> optionalAt [ "payload", "verteilstelle" ] Decode.int 0
         |> optionalAt [ "payload", "kontoinhaber" ] Decode.string ""
         |> optionalAt [ "payload", "mitglied" ] Decode.string ""
         |> optionalAt [ "payload", "adresse" ] Decode.string ""
         |> optionalAt [ "payload", "iban" ] Decode.string ""
         |> optionalAt [ "payload", "abbuchung" ] abbuchungDecoder Monatlich
         |> optional "offer" Offer.decoder Offer.NoOffer
+        |> optional "token" Decode.string ""
 
 
 bieterListDecoder : Decoder (List Bieter)
@@ -54,7 +56,7 @@ bieterEncoder bieter =
         , ( "teilpartner", Encode.string bieter.teilpartner )
         , ( "mail", Encode.string bieter.mail )
         , ( "teilpartnerMail", Encode.string bieter.teilpartnerMail )
-        , ( "verteilstelle", verteilEncoder bieter.verteilstelle )
+        , ( "verteilstelle", Encode.int bieter.verteilstelle )
         , ( "kontoinhaber", Encode.string bieter.kontoinhaber )
         , ( "mitglied", Encode.string bieter.mitglied )
         , ( "adresse", Encode.string bieter.adresse )
@@ -63,94 +65,6 @@ bieterEncoder bieter =
         ]
 
 
-type Verteilstelle
-    = AuswahlVerteilstelle
-    | Villingen
-    | Schwenningen
-    | Ueberauchen
-
-
-verteilDecoder : Decoder (Maybe Verteilstelle)
-verteilDecoder =
-    Decode.int |> Decode.andThen fromVerteilID
-
-
-verteilEncoder : Maybe Verteilstelle -> Encode.Value
-verteilEncoder verteiler =
-    case verteiler of
-        Nothing ->
-            Encode.null
-
-        Just AuswahlVerteilstelle ->
-            Encode.int 0
-        
-        Just Villingen ->
-            Encode.int 1
-
-        Just Schwenningen ->
-            Encode.int 2
-
-        Just Ueberauchen ->
-            Encode.int 3
-
-
-fromVerteilID : Int -> Decoder (Maybe Verteilstelle)
-fromVerteilID n =
-    case n of
-        0 ->
-            Decode.succeed Nothing
-
-        1 ->
-            Decode.succeed (Just Villingen)
-
-        2 ->
-            Decode.succeed (Just Schwenningen)
-
-        3 ->
-            Decode.succeed (Just Ueberauchen)
-
-        _ ->
-            Decode.fail ("Unbekannte Verteilstelle " ++ String.fromInt n)
-
-
-verteilerFromString : String -> Maybe Verteilstelle
-verteilerFromString s =
-    case s of
-        "Wähle deine Verteilstelle" ->
-            Just AuswahlVerteilstelle
-
-        "Villingen" ->
-            Just Villingen
-
-        "Schwenningen" ->
-            Just Schwenningen
-
-        "Überauchen" ->
-            Just Ueberauchen
-
-        _ ->
-            Nothing
-
-
-verteilerToString : Maybe Verteilstelle -> String
-verteilerToString maybeVerteiler =
-    case maybeVerteiler of
-        Nothing ->
-            "Unbekannte"
-
-        Just AuswahlVerteilstelle ->
-            "Wähle deine Verteilstelle"
-
-        Just Villingen ->
-            "Villingen"
-
-        Just Schwenningen ->
-            "Schwenningen"
-
-        Just Ueberauchen ->
-            "Überauchen"
-
-
 type Abbuchung
     = Jaehrlich
     | Monatlich
@@ -218,6 +132,18 @@ idFromString sid =
     ID sid
 
 
+{-| key returns the access token of the bieter, that is used in the urls. Only
+an admin can use the id instead.
+-}
+key : Bieter -> ID
+key bieter =
+    if bieter.token == "" then
+        bieter.id
+
+    else
+        ID bieter.token
+
+
 urlParser : Url.Parser.Parser (ID -> a) a
 urlParser =
     Url.Parser.custom "BIETER" (idFromString >> Just)
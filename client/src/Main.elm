@@ -142,7 +142,7 @@ update msg model =
                             ( session, Cmd.none )
 
                         Just bieter ->
-                            Session.loadBieter session ReceivedBieter bieter.id
+                            Session.loadBieter session ReceivedBieter (Bieter.key bieter)
 
                 ( _, cmdState ) =
                     Session.loadState session ReceivedState
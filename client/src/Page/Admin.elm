@@ -5,6 +5,8 @@ import Html exposing (..)
 import Html.Attributes exposing (..)
 import Html.Events exposing (..)
 import Http
+import Json.Decode as Decode
+import Json.Encode as Encode
 import Offer
 import Route
 import Session exposing (Session)
@@ -16,6 +18,7 @@ import IBAN.Types
 type alias Model =
     { session : Session
     , bieterList : Maybe (List Bieter.Bieter)
+    , formName : String
     , formPassword : String
     , fetchErrorMsg : Maybe String
     , setStateErrorMsg : Maybe String
@@ -26,8 +29,10 @@ type alias Model =
 type Msg
     = Reload
     | ReceivedBieter (Result Http.Error (List Bieter.Bieter))
+    | LoginFormSaveName String
     | LoginFormSavePassword String
     | LoginFormSubmit
+    | ReceivedLogin (Result Http.Error String)
     | SetState String
     | SetStateResult (Result Http.Error State.State)
     | SelectBieter Bieter.Bieter
@@ -45,7 +50,7 @@ init session =
             else
                 Cmd.none
     in
-    ( Model session Nothing "" Nothing Nothing Nothing
+    ( Model session Nothing "" "" Nothing Nothing Nothing
     , cmd
     )
 
@@ -59,6 +64,11 @@ update msg model =
         ReceivedBieter response ->
             fetchBieterResponse model response
 
+        LoginFormSaveName name ->
+            ( { model | formName = name }
+            , Cmd.none
+            )
+
         LoginFormSavePassword pw ->
             ( { model | formPassword = pw }
             , Cmd.none
@@ -69,13 +79,28 @@ update msg model =
                 ( model, Cmd.none )
 
             else
-                let
-                    session =
-                        Session.withAdmin (Just model.formPassword) model.session
-                in
-                ( { model | session = session }
-                , fetchBieterList session
-                )
+                ( model, login model.formName model.formPassword )
+
+        ReceivedLogin result ->
+            case result of
+                Ok token ->
+                    let
+                        session =
+                            Session.withAdmin (Just token) model.session
+                    in
+                    ( { model | session = session, formPassword = "", fetchErrorMsg = Nothing }
+                    , fetchBieterList session
+                    )
+
+                Err (Http.BadStatus 401) ->
+                    ( { model | fetchErrorMsg = Just "Name oder Passwort ist falsch" }
+                    , Cmd.none
+                    )
+
+                Err e ->
+                    ( { model | fetchErrorMsg = Just (buildErrorMessage e) }
+                    , Cmd.none
+                    )
 
         SetState state ->
             let
@@ -136,6 +161,30 @@ reload model =
     )
 
 
+{-| login requests a session token. An empty name is the admin with the admin
+password.
+-}
+login : String -> String -> Cmd Msg
+login name password =
+    Http.request
+        { method = "POST"
+        , headers = []
+        , url = "/api/login"
+        , body = Http.jsonBody (loginEncoder name password)
+        , expect = Http.expectJson ReceivedLogin (Decode.field "token" Decode.string)
+        , timeout = Nothing
+        , tracker = Nothing
+        }
+
+
+loginEncoder : String -> String -> Encode.Value
+loginEncoder name password =
+    Encode.object
+        [ ( "name", Encode.string name )
+        , ( "password", Encode.string password )
+        ]
+
+
 fetchBieterList : Session -> Cmd Msg
 fetchBieterList session =
     Http.request
@@ -167,7 +216,7 @@ fetchBieterResponse model response =
             case e of
                 Http.BadStatus status ->
                     if status == 401 then
-                        ( { model | session = Session.withAdmin Nothing model.session, fetchErrorMsg = Just "Passwort is falsch" }
+                        ( { model | session = Session.withAdmin Nothing model.session, fetchErrorMsg = Just "Die Anmeldung ist abgelaufen" }
                         , Cmd.none
                         )
 
@@ -261,6 +310,8 @@ viewStatusSelect model =
             , option [ selected (state == State.Registration) ] [ text (State.toString State.Registration) ]
             , option [ selected (state == State.Validation) ] [ text (State.toString State.Validation) ]
             , option [ selected (state == State.Offer) ] [ text (State.toString State.Offer) ]
+            , option [ selected (state == State.Closed) ] [ text (State.toString State.Closed) ]
+            , option [ selected (state == State.Contract) ] [ text (State.toString State.Contract) ]
             ]
         ]
 
@@ -322,12 +373,19 @@ viewLogin model =
         [ h1 [] [ text "Admin login" ]
         , maybeError model.fetchErrorMsg
         , Html.form [ onSubmit LoginFormSubmit ]
-            [ text "Passwort"
+            [ text "Name (leer für admin)"
+            , input
+                [ type_ "text"
+                , value model.formName
+                , onInput LoginFormSaveName
+                , autofocus True
+                ]
+                []
+            , text "Passwort"
             , input
                 [ type_ "password"
                 , value model.formPassword
                 , onInput LoginFormSavePassword
-                , autofocus True
                 ]
                 []
             , div []
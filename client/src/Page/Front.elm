@@ -13,6 +13,7 @@ import QRCode
 import Route exposing (Route(..))
 import Session exposing (Session)
 import Svg.Attributes as SvgA
+import Verteilstelle exposing (Verteilstelle)
 
 
 type alias Model =
@@ -27,6 +28,7 @@ type alias Model =
     , draftOffer : String
     , offerValid : Bool
     , offerErrorMsg : Maybe String
+    , verteilstellen : List Verteilstelle
     }
 
 
@@ -63,6 +65,7 @@ type Msg
     | SaveDraftOffer String
     | SendOffer
     | ReceiveOffer (Result Http.Error Offer.Offer)
+    | ReceivedVerteilstellen (Result Http.Error (List Verteilstelle))
 
 
 init : Session -> ( Model, Cmd Msg )
@@ -84,7 +87,9 @@ init session =
                 Just bieter ->
                     Offer.toInputString bieter.offer
     in
-    ( Model session Show Nothing bieterID "" Nothing Nothing False offer False Nothing, Cmd.none )
+    ( Model session Show Nothing bieterID "" Nothing Nothing False offer False Nothing []
+    , Verteilstelle.fetch ReceivedVerteilstellen
+    )
 
 
 update : Msg -> Model -> ( Model, Cmd Msg )
@@ -95,7 +100,7 @@ update msg model =
 
         GotoEditPage ->
             ( { model | page = Edit, draftBieter = Session.toBieter model.session }
-            , Cmd.none
+            , Verteilstelle.fetch ReceivedVerteilstellen
             )
 
         SaveNumber nr ->
@@ -174,7 +179,7 @@ update msg model =
             case ( maybeBieter, offer ) of
                 ( Just bieter, Offer.Offer _ _ ) ->
                     ( model
-                    , Offer.send ReceiveOffer (Session.headers model.session) (Bieter.idToString bieter.id) offer
+                    , Offer.send ReceiveOffer (Session.headers model.session) (Bieter.idToString (Bieter.key bieter)) offer
                     )
 
                 _ ->
@@ -211,6 +216,16 @@ update msg model =
                     , Cmd.none
                     )
 
+        ReceivedVerteilstellen result ->
+            case result of
+                Ok verteilstellen ->
+                    ( { model | verteilstellen = verteilstellen }, Cmd.none )
+
+                Err e ->
+                    ( { model | editErrorMsg = Just (buildErrorMessage e) }
+                    , Cmd.none
+                    )
+
 
 updateEditPage : Model -> EditPageMsg -> ( Model, Cmd Msg )
 updateEditPage model editMsg =
@@ -241,7 +256,7 @@ updateEditPage model editMsg =
                     )
 
                 SaveVerteilstelle verteiler ->
-                    ( { model | draftBieter = Just { bieter | verteilstelle = Bieter.verteilerFromString verteiler } }
+                    ( { model | draftBieter = Just { bieter | verteilstelle = String.toInt verteiler |> Maybe.withDefault 0 } }
                     , Cmd.none
                     )
 
@@ -326,7 +341,7 @@ updateBieter session bieter =
     Http.request
         { method = "PUT"
         , headers = Session.headers session
-        , url = "/api/bieter/" ++ Bieter.idToString bieter.id
+        , url = "/api/bieter/" ++ Bieter.idToString (Bieter.key bieter)
         , body = Http.jsonBody (Bieter.bieterEncoder bieter)
         , expect = Http.expectJson Received Bieter.bieterDecoder
         , timeout = Nothing
@@ -356,7 +371,7 @@ buildErrorMessage httpError =
         Http.BadStatus statusCode ->
             case statusCode of
                 404 ->
-                    "Unbekannte Bieternummer"
+                    "Unbekannter Zugangscode"
 
                 _ ->
                     "Request failed with status code: " ++ String.fromInt statusCode
@@ -378,7 +393,7 @@ view model =
         Just bieter ->
             case model.page of
                 Show ->
-                    viewBieter model.session model.session.baseURL bieter model.draftOffer model.offerErrorMsg model.offerValid
+                    viewBieter model.session model.session.baseURL model.verteilstellen bieter model.draftOffer model.offerErrorMsg model.offerValid
 
                 Edit ->
                     let
@@ -395,11 +410,11 @@ viewLogin loginData =
     { title = "Login title"
     , content =
         div []
-            [ h1 [] [ text "Mit Bieternummer anmelden" ]
+            [ h1 [] [ text "Mit Zugangscode anmelden" ]
             , maybeError loginData.loginErrorMsg
             , Html.form [ onSubmit RequestLogin ]
                 [ div []
-                    [ text "Bieternummer"
+                    [ text "Zugangscode"
                     , input
                         [ id "nummer"
                         , type_ "text"
@@ -459,8 +474,8 @@ maybeError errorMsg =
             text ""
 
 
-viewBieter : Session -> String -> Bieter.Bieter -> String -> Maybe String -> Bool -> { title : String, content : Html Msg }
-viewBieter session baseURL bieter draftOffer error offerValid =
+viewBieter : Session -> String -> List Verteilstelle -> Bieter.Bieter -> String -> Maybe String -> Bool -> { title : String, content : Html Msg }
+viewBieter session baseURL verteilstellen bieter draftOffer error offerValid =
     let
         maybeEditButton =
             if Permission.hasPerm Permission.CanEdit session then
@@ -476,10 +491,12 @@ viewBieter session baseURL bieter draftOffer error offerValid =
             , div []
                 [ text "Deine Bieternummer ist "
                 , strong [] [ text (Bieter.idToString bieter.id) ]
-                , text ". Merke sie dir gut. Du brauchst sie für die nächste Anmeldung"
+                , text ". Dein Zugangscode ist "
+                , strong [] [ text (Bieter.idToString (Bieter.key bieter)) ]
+                , text ". Merke ihn dir gut. Du brauchst ihn für die nächste Anmeldung"
                 ]
             , div [style "margin" "4px"] [ text ("E-Mail: " ++ bieter.mail) ]
-            , div [style "margin" "4px"] [ text ("Verteilstelle: " ++ Bieter.verteilerToString bieter.verteilstelle) ]
+            , div [style "margin" "4px"] [ text ("Verteilstelle: " ++ Verteilstelle.name verteilstellen bieter.verteilstelle) ]
             , div [style "margin" "4px"] [ text ("Kontoinhaber: " ++ bieter.kontoinhaber) ]
             , div [style "margin" "4px"] [ text ("Mitglied: " ++ bieter.mitglied) ]
             , div [style "margin" "4px"] [ text ("Adresse: " ++ bieter.adresse) ]
@@ -488,9 +505,9 @@ viewBieter session baseURL bieter draftOffer error offerValid =
             , div [style "margin" "4px"] [ text ("Teilpartner Name: " ++ bieter.teilpartner) ]
             , div [style "margin" "4px"] [ text ("Teilpartner E-Mail: " ++ bieter.teilpartnerMail) ]
             , maybeEditButton
-            , a [ href ("/api/bieter/" ++ Bieter.idToString bieter.id ++ "/pdf") ] [ text "Bietervertrag (PDF)" ]
+            , a [ href ("/api/bieter/" ++ Bieter.idToString (Bieter.key bieter) ++ "/pdf") ] [ text "Bietervertrag (PDF)" ]
             , viewOffer session bieter draftOffer error offerValid
-            , viewQRCode baseURL bieter.id
+            , viewQRCode baseURL (Bieter.key bieter)
             ]
     }
 
@@ -601,11 +618,9 @@ viewEdit model =
                         , div []
                             [ text "Verteilstelle: "
                             , select [ onInput SaveVerteilstelle ]
-                                [ option [ selected (bieter.verteilstelle == Just Bieter.AuswahlVerteilstelle) ] [ text "Wähle deine Verteilstelle" ]
-                                , option [ selected (bieter.verteilstelle == Just Bieter.Villingen) ] [ text "Villingen" ]
-                                , option [ selected (bieter.verteilstelle == Just Bieter.Schwenningen) ] [ text "Schwenningen" ]
-                                , option [ selected (bieter.verteilstelle == Just Bieter.Ueberauchen) ] [ text "Überauchen" ]
-                                ]
+                                (option [ value "0", selected (bieter.verteilstelle == 0) ] [ text "Wähle deine Verteilstelle" ]
+                                    :: List.map (viewVerteilstelleOption bieter.verteilstelle) model.verteilstellen
+                                )
                             ]
                         , div []
                             [ text "Kontoinhaber: "
@@ -678,6 +693,27 @@ viewEdit model =
             }
 
 
+{-| viewVerteilstelleOption shows a verteilstelle in the select. A full
+verteilstelle can not be chosen, unless the bieter already has it.
+-}
+viewVerteilstelleOption : Int -> Verteilstelle -> Html msg
+viewVerteilstelleOption chosen verteilstelle =
+    let
+        label =
+            if verteilstelle.full then
+                verteilstelle.name ++ " (voll)"
+
+            else
+                verteilstelle.name
+    in
+    option
+        [ value (String.fromInt verteilstelle.id)
+        , selected (verteilstelle.id == chosen)
+        , disabled (verteilstelle.full && verteilstelle.id /= chosen)
+        ]
+        [ text label ]
+
+
 toSession : Model -> Session
 toSession model =
     model.session
@@ -22,6 +22,8 @@ type Viewer
     | Guest
 
 
+{-| IsAdmin holds the session token from /api/login.
+-}
 type Admin
     = IsAdmin String
     | NoAdmin
@@ -56,8 +58,8 @@ navKey s =
 headers : Session -> List Http.Header
 headers s =
     case s.admin of
-        IsAdmin pw ->
-            [ Http.header "auth" pw ]
+        IsAdmin token ->
+            [ Http.header "auth" token ]
 
         NoAdmin ->
             []
@@ -80,7 +82,7 @@ toBieterID : Session -> Maybe Bieter.ID
 toBieterID s =
     case s.viewer of
         LoggedIn bieter ->
-            Just bieter.id
+            Just (Bieter.key bieter)
 
         Loading id ->
             Just id
@@ -92,7 +94,7 @@ toBieterID s =
 loggedIn : Session -> Bieter.Bieter -> ( Session, Cmd msg )
 loggedIn session bieter =
     ( { session | viewer = LoggedIn bieter }
-    , Ports.send (Ports.StoreBieterID bieter.id)
+    , Ports.send (Ports.StoreBieterID (Bieter.key bieter))
     )
 
 
@@ -119,10 +121,10 @@ isAdmin session =
 
 
 withAdmin : Maybe String -> Session -> Session
-withAdmin maybePassword session =
-    case maybePassword of
-        Just pw ->
-            { session | admin = IsAdmin pw }
+withAdmin maybeToken session =
+    case maybeToken of
+        Just token ->
+            { session | admin = IsAdmin token }
 
         Nothing ->
             { session | admin = NoAdmin }
@@ -12,6 +12,8 @@ type State
     | Registration
     | Validation
     | Offer
+    | Closed
+    | Contract
 
 
 unknown : String
@@ -39,6 +41,16 @@ offer =
     "Bieten"
 
 
+closed : String
+closed =
+    "Abgeschlossen"
+
+
+contract : String
+contract =
+    "Vertrag"
+
+
 toString : State -> String
 toString state =
     case state of
@@ -57,6 +69,12 @@ toString state =
         Offer ->
             offer
 
+        Closed ->
+            closed
+
+        Contract ->
+            contract
+
 
 fromString : String -> State
 fromString state =
@@ -72,6 +90,12 @@ fromString state =
     else if state == offer then
         Offer
 
+    else if state == closed then
+        Closed
+
+    else if state == contract then
+        Contract
+
     else
         Unknown
 
@@ -94,6 +118,12 @@ stateDecoder n =
         3 ->
             Offer
 
+        4 ->
+            Closed
+
+        5 ->
+            Contract
+
         _ ->
             Unknown
 
@@ -112,6 +142,12 @@ stateEncoder state =
                 Offer ->
                     3
 
+                Closed ->
+                    4
+
+                Contract ->
+                    5
+
                 _ ->
                     0
     in
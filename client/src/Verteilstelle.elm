@@ -0,0 +1,42 @@
+module Verteilstelle exposing (Verteilstelle, fetch, name)
+
+import Http
+import Json.Decode as Decode exposing (Decoder)
+import Json.Decode.Pipeline exposing (required)
+
+
+type alias Verteilstelle =
+    { id : Int
+    , name : String
+    , full : Bool
+    }
+
+
+decoder : Decoder Verteilstelle
+decoder =
+    Decode.succeed Verteilstelle
+        |> required "id" Decode.int
+        |> required "name" Decode.string
+        |> required "full" Decode.bool
+
+
+{-| name returns the name of the verteilstelle with the id.
+-}
+name : List Verteilstelle -> Int -> String
+name verteilstellen id =
+    case List.filter (\v -> v.id == id) verteilstellen of
+        verteilstelle :: _ ->
+            verteilstelle.name
+
+        [] ->
+            "Unbekannte"
+
+
+fetch : (Result Http.Error (List Verteilstelle) -> msg) -> Cmd msg
+fetch result =
+    Http.get
+        { url = "/api/verteilstelle"
+        , expect =
+            Decode.list decoder
+                |> Http.expectJson result
+        }
@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"embed"
+	"flag"
+	"fmt"
 	"log"
 	"math/rand"
 	"os"
@@ -30,6 +32,14 @@ var defaultStatic embed.FS
 
 func main() {
 	rand.Seed(time.Now().Unix())
+
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+
 	ctx, cancel := withShutdown(context.Background())
 	defer cancel()
 
@@ -44,6 +54,42 @@ func main() {
 	}
 }
 
+// runCommand runs a command instead of the server.
+func runCommand(cmd string, args []string) error {
+	switch cmd {
+	case "compact":
+		return server.Compact(configFile, dbFile)
+
+	case "import":
+		flags := flag.NewFlagSet("import", flag.ExitOnError)
+		dryRun := flags.Bool("dry-run", false, "only validate the file")
+		flags.Parse(args)
+		if flags.NArg() != 1 {
+			return fmt.Errorf("usage: bieterrunde import [-dry-run] FILE.csv")
+		}
+		return server.Import(configFile, dbFile, flags.Arg(0), *dryRun)
+
+	case "rollover":
+		flags := flag.NewFlagSet("rollover", flag.ExitOnError)
+		selection := flags.String("bieter", "offer", `bieters to carry over: "offer" or "all"`)
+		keepTokens := flags.Bool("keep-tokens", false, "keep the access tokens of the bieters")
+		flags.Parse(args)
+		return server.Rollover(configFile, dbFile, *selection, flags.Args(), *keepTokens)
+
+	case "replay":
+		flags := flag.NewFlagSet("replay", flag.ExitOnError)
+		outFile := flags.String("o", "", "output file (.csv or .xlsx). Default is csv on stdout")
+		flags.Parse(args)
+		if flags.NArg() != 1 {
+			return fmt.Errorf(`usage: bieterrunde replay [-o FILE] SEQ|DATE|"DATE TIME"`)
+		}
+		return server.Replay(configFile, dbFile, flags.Arg(0), *outFile)
+
+	default:
+		return fmt.Errorf("unknown command %q. Available commands: compact, import, replay, rollover", cmd)
+	}
+}
+
 func withShutdown(ctx context.Context) (context.Context, context.CancelFunc) {
 	ctx, cancel := context.WithCancel(context.Background())
 	go func() {
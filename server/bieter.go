@@ -0,0 +1,180 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxFieldLength is the maximum number of characters of a text field.
+const maxFieldLength = 200
+
+// bieterData is the payload of a bieter.
+//
+// All fields are optional when a bieter is created, so the data can be filled
+// in step by step. validateComplete checks, that everything is there, that is
+// needed for the contract.
+type bieterData struct {
+	Name            string        `json:"name"`
+	Teilpartner     string        `json:"teilpartner"`
+	Mail            string        `json:"mail"`
+	TeilpartnerMail string        `json:"teilpartnerMail"`
+	Verteilstelle   verteilstelle `json:"verteilstelle"`
+	Kontoinhaber    string        `json:"kontoinhaber"`
+	Mitglied        string        `json:"mitglied"`
+	Adresse         string        `json:"adresse"`
+	IBAN            string        `json:"iban"`
+	Abbuchung       abbuchung     `json:"abbuchung"`
+}
+
+// decodeBieterData decodes and validates the payload of a bieter.
+func decodeBieterData(payload json.RawMessage) (bieterData, error) {
+	var data bieterData
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&data); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return bieterData{}, fieldErrors{{typeErr.Field, "Ungültiger Datentyp"}}
+		}
+
+		if field := strings.TrimPrefix(err.Error(), "json: unknown field "); field != err.Error() {
+			return bieterData{}, fieldErrors{{strings.Trim(field, `"`), "Unbekanntes Feld"}}
+		}
+
+		return bieterData{}, validationError{"Die Daten müssen ein JSON-Objekt sein"}
+	}
+
+	if err := data.validate(); err != nil {
+		return bieterData{}, err
+	}
+	return data, nil
+}
+
+// validate checks the fields, that are set.
+func (d bieterData) validate() error {
+	var errs fieldErrors
+
+	for _, f := range []struct {
+		name  string
+		value string
+	}{
+		{"name", d.Name},
+		{"teilpartner", d.Teilpartner},
+		{"mail", d.Mail},
+		{"teilpartnerMail", d.TeilpartnerMail},
+		{"kontoinhaber", d.Kontoinhaber},
+		{"mitglied", d.Mitglied},
+		{"adresse", d.Adresse},
+		{"iban", d.IBAN},
+	} {
+		if utf8.RuneCountInString(f.value) > maxFieldLength {
+			errs = append(errs, fieldError{f.name, fmt.Sprintf("Darf höchstens %d Zeichen lang sein", maxFieldLength)})
+		}
+	}
+
+	if strings.TrimSpace(d.Name) == "" {
+		errs = append(errs, fieldError{"name", "Der Name fehlt"})
+	}
+
+	if d.Mail != "" && !validMail(d.Mail) {
+		errs = append(errs, fieldError{"mail", "Keine gültige E-Mail-Adresse"})
+	}
+
+	if d.TeilpartnerMail != "" && !validMail(d.TeilpartnerMail) {
+		errs = append(errs, fieldError{"teilpartnerMail", "Keine gültige E-Mail-Adresse"})
+	}
+
+	if d.Verteilstelle < 0 {
+		errs = append(errs, fieldError{"verteilstelle", "Unbekannte Verteilstelle"})
+	}
+
+	if d.IBAN != "" {
+		if err := validateIBAN(d.IBAN); err != nil {
+			errs = append(errs, fieldError{"iban", err.Error()})
+		}
+	}
+
+	if d.Abbuchung != abbuchungMonthly && d.Abbuchung != abbuchungYearly {
+		errs = append(errs, fieldError{"abbuchung", "Die Abbuchung muss monatlich oder jährlich sein"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateComplete checks, that all fields are set, that are needed for the
+// contract.
+func (d bieterData) validateComplete() error {
+	var errs fieldErrors
+	if d.Mail == "" {
+		errs = append(errs, fieldError{"mail", "Die E-Mail-Adresse fehlt"})
+	}
+
+	if d.Verteilstelle == 0 {
+		errs = append(errs, fieldError{"verteilstelle", "Keine Verteilstelle ausgewählt"})
+	}
+
+	if d.Adresse == "" {
+		errs = append(errs, fieldError{"adresse", "Die Adresse fehlt"})
+	}
+
+	if d.IBAN == "" {
+		errs = append(errs, fieldError{"iban", "Die IBAN fehlt"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validMail(address string) bool {
+	parsed, err := mail.ParseAddress(address)
+	return err == nil && parsed.Address == address
+}
+
+// verteilstelle is the id of a Verteilstelle. 0 means, that no verteilstelle
+// is chosen.
+type verteilstelle int
+
+type abbuchung int
+
+const (
+	abbuchungMonthly abbuchung = 0
+	abbuchungYearly  abbuchung = 1
+)
+
+func (a abbuchung) String() string {
+	if a == abbuchungYearly {
+		return "Jährlich"
+	}
+	return "Monatlich"
+}
+
+// fieldError is a validation error of one field of the bieter data.
+type fieldError struct {
+	Field string
+	Msg   string
+}
+
+// fieldErrors are all validation errors of the bieter data.
+type fieldErrors []fieldError
+
+func (e fieldErrors) Error() string {
+	return e.forClient()
+}
+
+func (e fieldErrors) forClient() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = fmt.Sprintf("%s: %s", err.Field, err.Msg)
+	}
+	return "Ungültige Daten: " + strings.Join(msgs, "; ")
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDecodeBieterData(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		payload string
+		fields  []string
+	}{
+		{"only name", `{"name":"hugo"}`, nil},
+		{
+			"from client",
+			`{"name":"hugo","teilpartner":"","mail":"hugo@example.com","teilpartnerMail":"","verteilstelle":null,"kontoinhaber":"","mitglied":"","adresse":"","iban":"","abbuchung":0}`,
+			nil,
+		},
+		{"no name", `{"mail":"hugo@example.com"}`, []string{"name"}},
+		{"invalid mail", `{"name":"hugo","mail":"hugo"}`, []string{"mail"}},
+		{"negative verteilstelle", `{"name":"hugo","verteilstelle":-1}`, []string{"verteilstelle"}},
+		{"invalid abbuchung", `{"name":"hugo","abbuchung":2}`, []string{"abbuchung"}},
+		{"wrong type", `{"name":"hugo","verteilstelle":"Villingen"}`, []string{"verteilstelle"}},
+		{"unknown field", `{"name":"hugo","foo":1}`, []string{"foo"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeBieterData([]byte(tt.payload))
+
+			if tt.fields == nil {
+				if err != nil {
+					t.Fatalf("got error: %v", err)
+				}
+				return
+			}
+
+			var errs fieldErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("got error %v, expected field errors", err)
+			}
+
+			if len(errs) != len(tt.fields) {
+				t.Fatalf("got errors %v, expected fields %v", errs, tt.fields)
+			}
+
+			for i, field := range tt.fields {
+				if errs[i].Field != field {
+					t.Errorf("error %d is for field %q, expected %q", i, errs[i].Field, field)
+				}
+			}
+		})
+	}
+}
@@ -6,22 +6,114 @@ import (
 	"log"
 	"math/rand"
 	"os"
+	"time"
 
 	"github.com/pelletier/go-toml/v2"
 )
 
 // Config does what it is named.
 type Config struct {
-	AdminPW    string `toml:"admin_password"`
+	// AdminPWHash is the bcrypt hash of the admin password. It can be
+	// created with tools/config. AdminPW is the old plaintext password. It is
+	// only used, if there is no hash.
+	AdminPWHash string `toml:"admin_password_hash"`
+	AdminPW     string `toml:"admin_password"`
+
+	// Users are the admin accounts with their roles.
+	Users []UserConfig `toml:"users"`
+
+	// SessionHours is the time an admin session is valid.
+	SessionHours int `toml:"session_hours"`
+
 	ListenAddr string `toml:"listen_addr"`
 	Domain     string `toml:"domain"`
+
+	// DBBackend is the event store. Possible values are "file", "sqlite" and
+	// "memory".
+	DBBackend string `toml:"db_backend"`
+	DBFile    string `toml:"db_file"`
+
+	// MinOffer is the lowest monthly offer in cent, a bieter can make.
+	MinOffer int `toml:"min_offer"`
+
+	// GuideMinPercent and GuideMaxPercent are the suggested range for an
+	// offer in percent of the guide value.
+	GuideMinPercent int `toml:"guide_min_percent"`
+	GuideMaxPercent int `toml:"guide_max_percent"`
+
+	// ContractFile is the template of the bietervertrag. Defaults to
+	// contract.toml next to the config file. If the file does not exist, the
+	// built-in template is used.
+	ContractFile string `toml:"contract_file"`
+
+	// ArchiveDir is the directory with the event logs of old seasons.
+	ArchiveDir string `toml:"archive_dir"`
+
+	Export    ExportConfig    `toml:"export"`
+	Season    SeasonConfig    `toml:"season"`
+	SEPA      SEPAConfig      `toml:"sepa"`
+	RateLimit RateLimitConfig `toml:"rate_limit"`
+}
+
+// ExportConfig are the defaults for the csv and xlsx export of the bieters.
+type ExportConfig struct {
+	// Columns are the exported columns. Empty means all columns.
+	Columns []string `toml:"columns"`
+
+	// Sort is the column to sort by. With a leading "-", the order is
+	// descending.
+	Sort string `toml:"sort"`
+}
+
+// SEPAConfig is the account of the association, that collects the offers.
+type SEPAConfig struct {
+	CreditorName string `toml:"creditor_name"`
+	CreditorIBAN string `toml:"creditor_iban"`
+	CreditorBIC  string `toml:"creditor_bic"`
+
+	// CreditorID is the Gläubiger-Identifikationsnummer of the association.
+	CreditorID string `toml:"creditor_id"`
+
+	// MandateDate is the date, the mandates were signed, in the format
+	// 2006-01-02. Defaults to the start of the season.
+	MandateDate string `toml:"mandate_date"`
 }
 
 // DefaultConfig returns a config object with default values.
 func DefaultConfig() Config {
 	return Config{
+		SessionHours: 12,
+
 		ListenAddr: ":9600",
 		Domain:     "http://localhost:9600",
+		DBBackend:  "file",
+		ArchiveDir: "archive",
+
+		GuideMinPercent: 80,
+		GuideMaxPercent: 120,
+
+		Export: ExportConfig{
+			Sort: "name",
+		},
+
+		Season: SeasonConfig{
+			Start:           NewMonth(2022, time.April),
+			End:             NewMonth(2023, time.March),
+			ReferencePrefix: "22",
+		},
+
+		SEPA: SEPAConfig{
+			CreditorName: "Solidarische Landwirtschaft Baarfood e.V.",
+			CreditorID:   "DE62ZZZ00001997635",
+		},
+
+		RateLimit: RateLimitConfig{
+			Failures: 10,
+			Delay:    1,
+			MaxDelay: 900,
+			Alert:    50,
+			Reset:    3600,
+		},
 	}
 }
 
@@ -43,6 +135,20 @@ func LoadConfig(file string) (Config, error) {
 	if err := toml.NewDecoder(f).Decode(&c); err != nil {
 		return Config{}, fmt.Errorf("reading config: %w", err)
 	}
+
+	if err := c.Season.validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid season: %w", err)
+	}
+
+	for _, u := range c.Users {
+		if err := u.validate(); err != nil {
+			return Config{}, fmt.Errorf("invalid user: %w", err)
+		}
+	}
+
+	if c.AdminPWHash == "" && c.AdminPW != "" {
+		log.Println("Warning: admin_password is saved in plaintext. Use admin_password_hash instead.")
+	}
 	return c, nil
 }
 
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"bytes"
+	_ "embed" // Needed for the default contract template.
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+//go:embed contract.toml
+var defaultContract []byte
+
+// germanMonths are the names of the months starting with january.
+var germanMonths = [...]string{
+	"Januar", "Februar", "März", "April", "Mai", "Juni",
+	"Juli", "August", "September", "Oktober", "November", "Dezember",
+}
+
+// ContractTemplate is the content of the bietervertrag.
+//
+// All texts are go templates, that are executed with contractData.
+type ContractTemplate struct {
+	// Header is the address of the association in the upper left corner.
+	Header []string `toml:"header"`
+
+	Contract struct {
+		Title      string   `toml:"title"`
+		Paragraphs []string `toml:"paragraphs"`
+	} `toml:"contract"`
+
+	SEPA struct {
+		Title      string   `toml:"title"`
+		Monthly    string   `toml:"monthly"`
+		Yearly     string   `toml:"yearly"`
+		Amount     string   `toml:"amount"`
+		Paragraphs []string `toml:"paragraphs"`
+	} `toml:"sepa"`
+
+	templates map[string]*template.Template
+}
+
+// LoadContractTemplate loads the contract template from a toml file.
+//
+// If the file does not exist, the built-in template is used.
+func LoadContractTemplate(file string) (*ContractTemplate, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("reading contract template: %w", err)
+		}
+		content = defaultContract
+	}
+
+	ct, err := parseContractTemplate(content)
+	if err != nil {
+		return nil, fmt.Errorf("contract template %s: %w", file, err)
+	}
+	return ct, nil
+}
+
+func parseContractTemplate(content []byte) (*ContractTemplate, error) {
+	var ct ContractTemplate
+	decoder := toml.NewDecoder(bytes.NewReader(content))
+	decoder.SetStrict(true)
+	if err := decoder.Decode(&ct); err != nil {
+		var strictErr *toml.StrictMissingError
+		if errors.As(err, &strictErr) {
+			return nil, fmt.Errorf("unknown fields:\n%s", strictErr.String())
+		}
+		return nil, fmt.Errorf("decoding toml: %w", err)
+	}
+
+	texts := append([]string{ct.Contract.Title, ct.SEPA.Title, ct.SEPA.Monthly, ct.SEPA.Yearly, ct.SEPA.Amount}, ct.Header...)
+	texts = append(texts, ct.Contract.Paragraphs...)
+	texts = append(texts, ct.SEPA.Paragraphs...)
+
+	ct.templates = make(map[string]*template.Template, len(texts))
+	for _, text := range texts {
+		if _, ok := ct.templates[text]; ok {
+			continue
+		}
+
+		tmpl, err := template.New("").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("parsing text: %w", err)
+		}
+
+		// Execute the template once to find unknown placeholders.
+		if err := tmpl.Execute(new(strings.Builder), contractData{}); err != nil {
+			return nil, fmt.Errorf("executing text %q: %w", text, err)
+		}
+		ct.templates[text] = tmpl
+	}
+
+	return &ct, nil
+}
+
+// render executes one text of the template. Line breaks are replaced by
+// spaces.
+func (ct *ContractTemplate) render(text string, data contractData) (string, error) {
+	tmpl, ok := ct.templates[text]
+	if !ok {
+		return "", fmt.Errorf("text %q is not part of the template", text)
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("executing text %q: %w", text, err)
+	}
+	return strings.Join(strings.Fields(b.String()), " "), nil
+}
+
+// contractData are the values, that can be used in the contract template.
+type contractData struct {
+	bieterData
+
+	ID string
+
+	// Verteilstelle is printed as its name. Abbuchung is the name of the
+	// value of the bieter.
+	Verteilstelle Verteilstelle
+	Abbuchung     string
+
+	// Offer is the monthly offer and Amount the amount of one debit.
+	Offer  string
+	Amount string
+
+	Season           contractSeason
+	Creditor         string
+	CreditorID       string
+	MandateReference string
+}
+
+// contractSeason is the season formatted for the contract.
+type contractSeason struct {
+	// Name is like 2022/23.
+	Name string
+
+	// Start and End are the first and last month like April 2022.
+	Start string
+	End   string
+
+	// FirstDay is the first day of the season like 1. April 2022.
+	FirstDay string
+
+	// Months is the length of the season and Payments the number of monthly
+	// payments.
+	Months   int
+	Payments int
+}
+
+func newContractSeason(s SeasonConfig) contractSeason {
+	return contractSeason{
+		Name:     fmt.Sprintf("%d/%02d", s.Start.Year(), s.End.Year()%100),
+		Start:    germanMonth(s.Start.Time),
+		End:      germanMonth(s.End.Time),
+		FirstDay: fmt.Sprintf("%d. %s", s.Start.Day(), germanMonth(s.Start.Time)),
+		Months:   monthsBetween(s.Start.Time, s.End.Time) + 1,
+		Payments: s.payments(),
+	}
+}
+
+// germanMonth formats the month of t like April 2022.
+func germanMonth(t time.Time) string {
+	return fmt.Sprintf("%s %d", germanMonths[t.Month()-1], t.Year())
+}
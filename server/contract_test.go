@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/base64"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestContractTemplate(t *testing.T) {
+	ct, err := parseContractTemplate(defaultContract)
+	if err != nil {
+		t.Fatalf("parsing default template: %v", err)
+	}
+
+	data := contractData{
+		bieterData:    bieterData{Name: "Max Mustermann", Mail: "max@example.com"},
+		Verteilstelle: Verteilstelle{ID: 2, Name: "Schwenningen"},
+		Abbuchung:     "monatlich",
+		Season:        newContractSeason(DefaultConfig().Season),
+		Creditor:      "Solawi",
+	}
+
+	got, err := ct.render(ct.Contract.Paragraphs[3], data)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if expect := "Ich hole meinen Anteil in der Verteilstelle in Schwenningen."; got != expect {
+		t.Errorf("got %q, expected %q", got, expect)
+	}
+
+	got, err = ct.render(ct.Contract.Paragraphs[0], data)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	expect := "Ich, Max Mustermann (E-Mail: max@example.com), bin Mitglied im Verein Solawi und möchte im Gemüsejahr 2022/23 (April 2022 – März 2023) einen Gemüseanteil beziehen."
+	if got != expect {
+		t.Errorf("got\n%s\nexpected\n%s", got, expect)
+	}
+
+	img, err := os.ReadFile("../static/images/pdf_header_image.png")
+	if err != nil {
+		t.Fatalf("reading header image: %v", err)
+	}
+
+	if _, err := Bietervertrag(DefaultConfig(), ct, "1", base64.StdEncoding.EncodeToString(img), pdfData{bieterData: data.bieterData, offer: 5000}); err != nil {
+		t.Errorf("creating pdf: %v", err)
+	}
+}
+
+func TestContractTemplateInvalid(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		content string
+		errMsg  string
+	}{
+		{"unknown placeholder", `header = ["{{.Unknown}}"]`, "Unknown"},
+		{"invalid template", `header = ["{{.Name"]`, "parsing text"},
+		{"unknown field", `footer = "text"`, "footer"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseContractTemplate([]byte(tt.content))
+			if err == nil {
+				t.Fatalf("got no error")
+			}
+
+			if !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("got error %q, expected it to contain %q", err, tt.errMsg)
+			}
+		})
+	}
+}
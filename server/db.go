@@ -1,53 +1,95 @@
 package server
 
 import (
-	"bufio"
-	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"math/rand"
-	"os"
 	"strconv"
 	"sync"
-	"time"
 )
 
+// snapshotInterval is the number of events after which a new snapshot is
+// written.
+const snapshotInterval = 500
+
 // Database holds the data in memory and saves them to disk.
 type Database struct {
 	sync.RWMutex
-	file string
+	store EventStore
+
+	// events is the number of events in the store. snapshotAt is the number of
+	// events that are part of the last snapshot.
+	events     int
+	snapshotAt int
+
+	// storeErr is set, when an event could not be saved. The events after it
+	// are already executed, so the memory does not match the store anymore.
+	storeErr error
 
 	bieter map[string]json.RawMessage
 	offer  map[string]int
 	state  ServiceState
+
+	// tokens maps the access tokens to the bieter ids. bieterToken is the
+	// other direction.
+	tokens      map[string]string
+	bieterToken map[string]string
+
+	// round is the current bidding round, starting with 1. rounds contains
+	// the offers of the finished rounds. rounds[0] are the offers of round 1.
+	round  int
+	rounds []map[string]int
+
+	budget Budget
+
+	schedule       map[int]ScheduledState
+	lastScheduleID int
+
+	verteilstellen      map[int]Verteilstelle
+	lastVerteilstelleID int
 }
 
-// NewDB load the db from file.
-func NewDB(file string) (*Database, error) {
-	db, err := openDB(file)
+// NewDB loads the db from an event store.
+func NewDB(store EventStore) (*Database, error) {
+	db, err := openDB(store)
 	if err != nil {
 		return nil, fmt.Errorf("open database: %w", err)
 	}
 
-	db.file = file
+	db.store = store
 	return db, nil
 }
 
-func openDB(file string) (*Database, error) {
-	f, err := os.Open(file)
-	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return emptyDatabase(), nil
+func openDB(store EventStore) (*Database, error) {
+	db := emptyDatabase()
+
+	if s, ok := store.(Snapshotter); ok {
+		bs, err := s.LoadSnapshot()
+		if err != nil {
+			return nil, fmt.Errorf("loading snapshot: %w", err)
+		}
+
+		if bs != nil {
+			db, err = loadSnapshot(bs)
+			if err != nil {
+				return nil, fmt.Errorf("loading snapshot: %w", err)
+			}
 		}
-		return nil, fmt.Errorf("open database file: %w", err)
 	}
-	defer f.Close()
 
-	db, err := loadDatabase(f)
-	if err != nil {
-		return nil, fmt.Errorf("loading database: %w", err)
+	if err := db.replay(store.Iterate, db.snapshotAt, ReplayLimit{}); err != nil {
+		if !errors.Is(err, errSnapshotMismatch) {
+			return nil, fmt.Errorf("loading database: %w", err)
+		}
+
+		log.Printf("Warning: %v. Ignoring snapshot", err)
+		db = emptyDatabase()
+		if err := db.replay(store.Iterate, 0, ReplayLimit{}); err != nil {
+			return nil, fmt.Errorf("loading database: %w", err)
+		}
 	}
 	return db, nil
 }
@@ -57,92 +99,157 @@ func emptyDatabase() *Database {
 		bieter: make(map[string]json.RawMessage),
 		offer:  make(map[string]int),
 		state:  stateRegistration,
+		round:  1,
+
+		tokens:      make(map[string]string),
+		bieterToken: make(map[string]string),
+
+		schedule:       make(map[int]ScheduledState),
+		verteilstellen: make(map[int]Verteilstelle),
 	}
 }
 
-func loadDatabase(r io.Reader) (*Database, error) {
+// loadDatabase creates a database from the events in r. The replay stops at
+// the limit.
+func loadDatabase(r io.Reader, limit ReplayLimit) (*Database, error) {
 	db := emptyDatabase()
+	iterate := func(fn func([]byte) error) error {
+		return scanLines(r, fn)
+	}
 
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		line := bytes.TrimSpace(scanner.Bytes())
-		if len(line) == 0 {
-			continue
+	if err := db.replay(iterate, 0, limit); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// replay executes the events from iterate. The first skip events are not
+// executed, since they are already part of the database state. The events
+// after the limit are not executed.
+func (db *Database) replay(iterate func(func([]byte) error) error, skip int, limit ReplayLimit) error {
+	err := iterate(func(record []byte) error {
+		db.events++
+		if db.events <= skip {
+			return nil
 		}
 
-		var typer struct {
-			Type    string          `json:"type"`
-			Payload json.RawMessage `json:"payload"`
+		r, err := decodeRecord(record, db.events)
+		if err != nil {
+			return err
 		}
-		if err := json.Unmarshal(line, &typer); err != nil {
-			return nil, fmt.Errorf("decoding event: %w", err)
+
+		if limit.reached(r) {
+			db.events--
+			return errReplayLimit
 		}
 
-		event := getEvent(typer.Type)
-		if event == nil {
-			return nil, fmt.Errorf("Unknown event %q, payload %q", typer.Type, typer.Payload)
+		if r.Seq != db.events {
+			log.Printf("Warning: event %d has the sequence number %d", db.events, r.Seq)
 		}
 
-		if err := json.Unmarshal(typer.Payload, &event); err != nil {
-			return nil, fmt.Errorf("loading event %q: %w", typer.Type, err)
+		event, err := r.event()
+		if err != nil {
+			return err
 		}
 
 		if err := event.execute(db); err != nil {
-			return nil, fmt.Errorf("executing event %q: %w", typer.Type, err)
+			return fmt.Errorf("executing event %q: %w", r.Type, err)
 		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errReplayLimit) {
+		return err
 	}
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("scanning events: %w", err)
+
+	if db.events < skip {
+		return fmt.Errorf("%w: snapshot has %d events, store only %d", errSnapshotMismatch, skip, db.events)
 	}
 
-	return db, nil
+	return nil
 }
 
-func (db *Database) writeEvent(e Event) (err error) {
-	db.Lock()
-	defer db.Unlock()
+// Close closes the event store.
+func (db *Database) Close() error {
+	return db.store.Close()
+}
 
-	if err := e.validate(db); err != nil {
-		return fmt.Errorf("validating event: %w", err)
-	}
+// writeEvent validates, saves and executes an event.
+//
+// The event is executed before it is durable so other events can be written
+// in the meantime. writeEvent returns after the store has saved the event.
+//
+// The user and the request data of the user are saved with the event.
+func (db *Database) writeEvent(e Event, user User) error {
+	return db.writeEventFunc(func() Event { return e }, user)
+}
 
-	f, err := os.OpenFile(db.file, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+// writeEventFunc is like writeEvent, but the event is created by create while
+// the database is locked. This is for events, that depend on the current
+// state of the database.
+func (db *Database) writeEventFunc(create func() Event, user User) error {
+	wait, err := db.appendEvent(create, user)
 	if err != nil {
-		return fmt.Errorf("open db file: %w", err)
+		return err
 	}
-	defer func() {
-		wErr := f.Close()
-		if err != nil {
-			err = wErr
+
+	if err := wait(); err != nil {
+		db.Lock()
+		if db.storeErr == nil {
+			db.storeErr = err
 		}
-	}()
+		db.Unlock()
+		return fmt.Errorf("saving event: %w", err)
+	}
+	return nil
+}
+
+func (db *Database) appendEvent(create func() Event, user User) (func() error, error) {
+	db.Lock()
+	defer db.Unlock()
+
+	if db.storeErr != nil {
+		return nil, fmt.Errorf("database is broken and has to be restarted: %w", db.storeErr)
+	}
 
-	event := struct {
-		Type    string `json:"type"`
-		Time    string `json:"time"`
-		Payload Event  `json:"payload"`
-	}{
-		e.Name(),
-		time.Now().Format("2006-01-02 15:04:05"),
-		e,
+	e := create()
+
+	if err := e.validate(db); err != nil {
+		return nil, fmt.Errorf("validating event: %w", err)
 	}
 
-	bs, err := json.Marshal(event)
+	bs, err := encodeEvent(e, db.events+1, user)
 	if err != nil {
-		return fmt.Errorf("encoding event: %w", err)
+		return nil, fmt.Errorf("encoding event: %w", err)
 	}
 
-	bs = append(bs, '\n')
+	wait := db.store.Append(bs)
+	db.events++
 
-	if _, err := f.Write(bs); err != nil {
-		return fmt.Errorf("writing event to file: %q: %w", bs, err)
+	if err := e.execute(db); err != nil {
+		return nil, fmt.Errorf("executing event: %w", err)
 	}
 
-	if err := e.execute(db); err != nil {
-		return fmt.Errorf("executing event: %w", err)
+	if db.events-db.snapshotAt >= snapshotInterval {
+		if err := db.writeSnapshot(); err != nil {
+			// The snapshot is only an optimization. The event is already
+			// saved, so the write does not fail.
+			log.Printf("Warning: writing snapshot: %v", err)
+		}
 	}
 
-	return nil
+	return wait, nil
+}
+
+// encodeEvent returns the record, that is saved in the event store for an
+// event. seq is the number of the event in the store. user is the user, that
+// created the event. It is the zero value for bieters.
+func encodeEvent(e Event, seq int, user User) ([]byte, error) {
+	record, err := newEventRecord(e, seq, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(record)
 }
 
 // ServiceState is the state of the service.
@@ -153,10 +260,45 @@ const (
 	stateRegistration
 	stateValidation
 	stateOffer
+	stateClosed
+	stateContract
+
+	// stateEnd is not a valid state. It is the first number after the last
+	// state.
+	stateEnd
 )
 
 func (s ServiceState) String() string {
-	return [...]string{"0 - Ungültig", "1 - Registrierung", "2 - Überprüfung", "3 - Gebote"}[s]
+	if s < stateInvalid || s >= stateEnd {
+		s = stateInvalid
+	}
+	return [...]string{"0 - Ungültig", "1 - Registrierung", "2 - Überprüfung", "3 - Gebote", "4 - Abgeschlossen", "5 - Vertrag"}[s]
+}
+
+// valid returns true, if the state can be set.
+func (s ServiceState) valid() bool {
+	return s > stateInvalid && s < stateEnd
+}
+
+// bieterEditable returns true, if a bieter can change or delete its data.
+func (s ServiceState) bieterEditable() bool {
+	return s == stateRegistration
+}
+
+// offerEditable returns true, if a bieter can change its offer.
+func (s ServiceState) offerEditable() bool {
+	return s == stateOffer
+}
+
+// offerFinal returns true, if the offers can not be changed anymore, not even
+// by an admin.
+func (s ServiceState) offerFinal() bool {
+	return s == stateClosed || s == stateContract
+}
+
+// contractAvailable returns true, if a bieter can download its contract.
+func (s ServiceState) contractAvailable() bool {
+	return s == stateContract
 }
 
 // Bieter returns the  data for a bieterID.
@@ -183,16 +325,16 @@ func (db *Database) BieterList() map[string]json.RawMessage {
 }
 
 // NewBieter creates a new bieter and returns its id.
-func (db *Database) NewBieter(payload json.RawMessage, asAdmin bool) (string, error) {
+func (db *Database) NewBieter(payload json.RawMessage, user User) (string, error) {
 	var id string
 	for {
 		id = strconv.Itoa(rand.Intn(100_000_000))
-		event, err := newEventCreate(id, payload, asAdmin)
+		event, err := newEventCreate(id, payload, user.can(permWrite))
 		if err != nil {
 			return "", fmt.Errorf("invalid event: %w", err)
 		}
 
-		if err := db.writeEvent(event); err != nil {
+		if err := db.writeEvent(event, user); err != nil {
 			if errors.Is(err, errIDExists) {
 				continue
 			}
@@ -204,9 +346,34 @@ func (db *Database) NewBieter(payload json.RawMessage, asAdmin bool) (string, er
 	return id, nil
 }
 
+// importBieter creates a bieter for each payload with one event. Either all
+// bieters are created or none. Returns the ids in the order of the payloads.
+func (db *Database) importBieter(payloads []json.RawMessage, user User) ([]string, error) {
+	for {
+		ids := make([]string, len(payloads))
+		bieter := make([]eventUpdate, len(payloads))
+		for i, payload := range payloads {
+			ids[i] = strconv.Itoa(rand.Intn(100_000_000))
+			event, err := newEventCreate(ids[i], payload, user.can(permWrite))
+			if err != nil {
+				return nil, fmt.Errorf("invalid event: %w", err)
+			}
+			bieter[i] = event
+		}
+
+		if err := db.writeEvent(newEventImport(bieter), user); err != nil {
+			if errors.Is(err, errIDExists) {
+				continue
+			}
+			return nil, fmt.Errorf("creating event: %w", err)
+		}
+		return ids, nil
+	}
+}
+
 // UpdateBieter updates an existing bieter. The new payload is read from r and
 // is returned (on success).
-func (db *Database) UpdateBieter(id string, r io.Reader, asAdmin bool) (json.RawMessage, error) {
+func (db *Database) UpdateBieter(id string, r io.Reader, user User) (json.RawMessage, error) {
 	payload, err := io.ReadAll(r)
 	if err != nil {
 		return nil, fmt.Errorf("reading body for update: %w", err)
@@ -215,23 +382,23 @@ func (db *Database) UpdateBieter(id string, r io.Reader, asAdmin bool) (json.Raw
 	event, err := newEventUpdate(
 		id,
 		payload,
-		asAdmin,
+		user.can(permWrite),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("creating update event: %w", err)
 	}
 
-	if err := db.writeEvent(event); err != nil {
+	if err := db.writeEvent(event, user); err != nil {
 		return nil, fmt.Errorf("writing update event: %w", err)
 	}
 	return payload, nil
 }
 
 // DeleteBieter removes a bieter.
-func (db *Database) DeleteBieter(id string, asAdmin bool) error {
-	event := newEventDelete(id, asAdmin)
+func (db *Database) DeleteBieter(id string, user User) error {
+	event := newEventDelete(id, user.can(permWrite))
 
-	if err := db.writeEvent(event); err != nil {
+	if err := db.writeEvent(event, user); err != nil {
 		return fmt.Errorf("writing delete event: %w", err)
 	}
 
@@ -247,7 +414,7 @@ func (db *Database) State() ServiceState {
 }
 
 // SetState updates the db state.
-func (db *Database) SetState(r io.Reader) error {
+func (db *Database) SetState(r io.Reader, user User) error {
 	var decoded struct {
 		State int `json:"state"`
 	}
@@ -260,7 +427,7 @@ func (db *Database) SetState(r io.Reader) error {
 		return fmt.Errorf("create state event: %w", err)
 	}
 
-	if err := db.writeEvent(event); err != nil {
+	if err := db.writeEvent(event, user); err != nil {
 		return fmt.Errorf("writing state event: %w", err)
 	}
 
@@ -277,8 +444,9 @@ func (db *Database) Offer(id string) int {
 
 // UpdateOffer sets the offer of a bieter.
 //
-// The offer is in cent. So 100 € would be 10_000
-func (db *Database) UpdateOffer(id string, r io.Reader, asAdmin bool) error {
+// The offer is in cent. So 100 € would be 10_000. minOffer is the lowest
+// offer a bieter is allowed to make.
+func (db *Database) UpdateOffer(id string, r io.Reader, minOffer int, user User) error {
 	var offer struct {
 		Offer int `json:"offer"`
 	}
@@ -286,29 +454,63 @@ func (db *Database) UpdateOffer(id string, r io.Reader, asAdmin bool) error {
 		return fmt.Errorf("decoding offer: %w", err)
 	}
 
-	event, err := newEventOffer(id, offer.Offer, asAdmin)
+	event, err := newEventOffer(id, offer.Offer, minOffer, user.can(permWrite))
 	if err != nil {
 		return fmt.Errorf("creating offer event: %w", err)
 	}
 
-	if err := db.writeEvent(event); err != nil {
+	if err := db.writeEvent(event, user); err != nil {
 		return fmt.Errorf("writing offer event: %w", err)
 	}
 
 	return nil
 }
 
-// ClearOffer creates an event to remove all offers
-func (db *Database) ClearOffer(asAdmin bool) error {
-	if !asAdmin {
-		// TODO: Create other error
-		return validationError{"Not allowed"}
+// RoundOffer is the offer of a bieter in one round.
+type RoundOffer struct {
+	Round int `json:"round"`
+	Offer int `json:"offer"`
+}
+
+// Round returns the current bidding round.
+func (db *Database) Round() int {
+	db.RLock()
+	defer db.RUnlock()
+
+	return db.round
+}
+
+// OfferHistory returns the offers of a bieter in the finished rounds.
+//
+// Rounds without an offer are skipped.
+func (db *Database) OfferHistory(id string) []RoundOffer {
+	db.RLock()
+	defer db.RUnlock()
+
+	var history []RoundOffer
+	for i, offers := range db.rounds {
+		offer, ok := offers[id]
+		if !ok {
+			continue
+		}
+		history = append(history, RoundOffer{Round: i + 1, Offer: offer})
+	}
+	return history
+}
+
+// StartRound archives the offers of the current round and starts the next
+// round without offers.
+func (db *Database) StartRound(user User) error {
+	if !user.can(permWrite) {
+		return errForbidden
 	}
 
-	event := newEventOfferClear()
+	create := func() Event {
+		return newEventRoundStart(db.round + 1)
+	}
 
-	if err := db.writeEvent(event); err != nil {
-		return fmt.Errorf("writing offer event clear: %w", err)
+	if err := db.writeEventFunc(create, user); err != nil {
+		return fmt.Errorf("writing round start event: %w", err)
 	}
 
 	return nil
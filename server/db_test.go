@@ -1,6 +1,9 @@
 package server
 
 import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
 	"strings"
 	"testing"
 )
@@ -12,7 +15,7 @@ func TestDatabaseLoad(t *testing.T) {
 	{"type":"update","payload":{"id":"1234","payload":{"name":"hugo","adresse":"beim wald"}}}
 	`
 
-	db, err := loadDatabase(strings.NewReader(events))
+	db, err := loadDatabase(strings.NewReader(events), ReplayLimit{})
 	if err != nil {
 		t.Fatalf("loadDatabase returned: %v", err)
 	}
@@ -33,3 +36,95 @@ func TestDatabaseLoad(t *testing.T) {
 		t.Errorf("bieter 4321 is %q, expected %q", u2, expectU2)
 	}
 }
+
+func TestDatabaseRounds(t *testing.T) {
+	events := `
+	{"type":"update","payload":{"id":"1234","payload":{"name":"hugo"}}}
+	{"type":"offer","payload":{"id":"1234","offer":5000}}
+	{"type":"round-start","payload":{"round":2}}
+	{"type":"round-start","payload":{"round":3}}
+	{"type":"offer","payload":{"id":"1234","offer":7000}}
+	`
+
+	db, err := loadDatabase(strings.NewReader(events), ReplayLimit{})
+	if err != nil {
+		t.Fatalf("loadDatabase returned: %v", err)
+	}
+
+	if got := db.Round(); got != 3 {
+		t.Errorf("round is %d, expected 3", got)
+	}
+
+	if got := db.Offer("1234"); got != 7000 {
+		t.Errorf("offer is %d, expected 7000", got)
+	}
+
+	history := db.OfferHistory("1234")
+	if len(history) != 1 || history[0] != (RoundOffer{Round: 1, Offer: 5000}) {
+		t.Errorf("history is %v, expected [{1 5000}]", history)
+	}
+}
+
+func TestOffersFinal(t *testing.T) {
+	db, err := NewDB(NewMemoryStore(
+		`{"type":"update","payload":{"id":"1","payload":{"name":"hugo"}}}`,
+		`{"type":"update","payload":{"id":"2","payload":{"name":"erik"}}}`,
+		`{"type":"offer","payload":{"id":"1","offer":5000}}`,
+		`{"type":"offer","payload":{"id":"2","offer":6000}}`,
+		`{"type":"delete","payload":{"id":"2"}}`,
+		`{"type":"state","payload":{"state":4}}`,
+	))
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+
+	if _, ok := db.offer["2"]; ok {
+		t.Errorf("deleted bieter still has an offer")
+	}
+
+	if err := db.StartRound(systemUser); err == nil {
+		t.Errorf("started a round after the offers are final")
+	}
+
+	if err := db.writeEvent(newEventOfferClear(), systemUser); err == nil {
+		t.Errorf("cleared the offers after they are final")
+	}
+
+	if err := db.DeleteBieter("1", systemUser); err == nil {
+		t.Errorf("deleted a bieter after the offers are final")
+	}
+
+	if _, err := db.UpdateBieter("1", strings.NewReader(`{"name":"hugo","iban":"DE02120300000000202051"}`), systemUser); err != nil {
+		t.Errorf("admin can not update a bieter after the offers are final: %v", err)
+	}
+
+	if got := db.Offer("1"); got != 5000 {
+		t.Errorf("offer is %d, expected 5000", got)
+	}
+}
+
+func TestForbidden(t *testing.T) {
+	db, err := NewDB(NewMemoryStore())
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+
+	treasurer := User{Name: "erika", Role: roleTreasurer}
+	if err := db.StartRound(treasurer); !errors.Is(err, errForbidden) {
+		t.Fatalf("StartRound returned %v, expected errForbidden", err)
+	}
+
+	w := httptest.NewRecorder()
+	handleError(w, fmt.Errorf("wrapped: %w", errForbidden))
+	if w.Code != 403 {
+		t.Errorf("handleError returned status %d, expected 403", w.Code)
+	}
+
+	round := db.Round()
+	if err := db.StartRound(systemUser); err != nil {
+		t.Fatalf("StartRound: %v", err)
+	}
+	if got := db.Round(); got != round+1 {
+		t.Errorf("round is %d, expected %d", got, round+1)
+	}
+}
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// Budget is the amount of money, the offers have to cover.
+type Budget struct {
+	// Total is the yearly budget in cent.
+	Total int `json:"total"`
+
+	// Shares is the number of shares, the budget is split into.
+	Shares int `json:"shares"`
+}
+
+// Evaluation is the result of the offers compared to the budget.
+//
+// All amounts are in cent.
+type Evaluation struct {
+	Budget Budget `json:"budget"`
+
+	// Offers is the number of bieters with an offer.
+	Offers int `json:"offers"`
+
+	// Sum is the yearly sum of all offers.
+	Sum int `json:"sum"`
+
+	// Coverage is the percentage of the budget, that is covered by Sum.
+	Coverage float64 `json:"coverage"`
+
+	// Shortfall is the amount, that is missing to cover the budget.
+	Shortfall int `json:"shortfall"`
+
+	// Average and Median are the monthly offers.
+	Average int `json:"average"`
+	Median  int `json:"median"`
+}
+
+// Guide is the guide value (Richtwert) for a monthly offer.
+//
+// All amounts are in cent.
+type Guide struct {
+	// Shares is the number of shares the budget is split into. If the budget
+	// does not define the shares, it is the number of bieters.
+	Shares int `json:"shares"`
+
+	// Value is the monthly amount for one share, so that the budget is
+	// covered.
+	Value int `json:"value"`
+
+	// Min and Max are the suggested range for an offer.
+	Min int `json:"min"`
+	Max int `json:"max"`
+
+	// MinOffer is the lowest offer, that is accepted.
+	MinOffer int `json:"min_offer"`
+}
+
+// Guide calculates the guide value from the budget.
+func (db *Database) Guide(c Config) Guide {
+	db.RLock()
+	defer db.RUnlock()
+
+	shares := db.budget.Shares
+	if shares == 0 {
+		shares = len(db.bieter)
+	}
+
+	return guide(db.budget.Total, shares, c)
+}
+
+func guide(total int, shares int, c Config) Guide {
+	g := Guide{
+		Shares:   shares,
+		MinOffer: c.MinOffer,
+	}
+
+	if shares == 0 {
+		return g
+	}
+
+	g.Value = total / shares / c.Season.payments()
+	g.Min = g.Value * c.GuideMinPercent / 100
+	g.Max = g.Value * c.GuideMaxPercent / 100
+
+	if g.Min < c.MinOffer {
+		g.Min = c.MinOffer
+	}
+	if g.Max < g.Min {
+		g.Max = g.Min
+	}
+	return g
+}
+
+// formatEuro formats an amount in cent as german euro value like 1.234,56 €.
+func formatEuro(cent int) string {
+	return formatDecimal(cent) + " €"
+}
+
+// formatDecimal formats an amount in cent as german number like 1.234,56.
+func formatDecimal(cent int) string {
+	sign := ""
+	if cent < 0 {
+		sign = "-"
+		cent = -cent
+	}
+
+	euro := strconv.Itoa(cent / 100)
+	for i := len(euro) - 3; i > 0; i -= 3 {
+		euro = euro[:i] + "." + euro[i:]
+	}
+
+	return fmt.Sprintf("%s%s,%02d", sign, euro, cent%100)
+}
+
+// Budget returns the budget.
+func (db *Database) Budget() Budget {
+	db.RLock()
+	defer db.RUnlock()
+
+	return db.budget
+}
+
+// SetBudget updates the budget.
+func (db *Database) SetBudget(r io.Reader, user User) error {
+	if !user.can(permWrite) {
+		return errForbidden
+	}
+
+	var budget Budget
+	if err := json.NewDecoder(r).Decode(&budget); err != nil {
+		return fmt.Errorf("decoding budget: %w", err)
+	}
+
+	event, err := newEventBudget(budget)
+	if err != nil {
+		return fmt.Errorf("creating budget event: %w", err)
+	}
+
+	if err := db.writeEvent(event, user); err != nil {
+		return fmt.Errorf("writing budget event: %w", err)
+	}
+
+	return nil
+}
+
+// Evaluation compares the offers of the current round with the budget.
+//
+// Only offers greater then 0 from existing bieters are used.
+func (db *Database) Evaluation(season SeasonConfig) Evaluation {
+	db.RLock()
+	defer db.RUnlock()
+
+	var offers []int
+	for id, offer := range db.offer {
+		if _, ok := db.bieter[id]; !ok || offer <= 0 {
+			continue
+		}
+		offers = append(offers, offer)
+	}
+
+	return evaluate(db.budget, offers, season.payments())
+}
+
+// evaluate calculates the evaluation. payments is the number of monthly
+// payments in the season.
+func evaluate(budget Budget, offers []int, payments int) Evaluation {
+	e := Evaluation{
+		Budget: budget,
+		Offers: len(offers),
+	}
+
+	if len(offers) == 0 {
+		e.Shortfall = budget.Total
+		return e
+	}
+
+	sort.Ints(offers)
+
+	var monthly int
+	for _, offer := range offers {
+		monthly += offer
+	}
+
+	e.Sum = monthly * payments
+	e.Average = monthly / len(offers)
+
+	middle := len(offers) / 2
+	e.Median = offers[middle]
+	if len(offers)%2 == 0 {
+		e.Median = (offers[middle-1] + offers[middle]) / 2
+	}
+
+	if budget.Total > 0 {
+		e.Coverage = float64(e.Sum) * 100 / float64(budget.Total)
+	}
+
+	if e.Sum < budget.Total {
+		e.Shortfall = budget.Total - e.Sum
+	}
+
+	return e
+}
@@ -0,0 +1,83 @@
+package server
+
+import "testing"
+
+func TestEvaluate(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		budget Budget
+		offers []int
+		expect Evaluation
+	}{
+		{
+			"no offers",
+			Budget{Total: 120_000, Shares: 2},
+			nil,
+			Evaluation{Budget: Budget{Total: 120_000, Shares: 2}, Shortfall: 120_000},
+		},
+		{
+			"odd number of offers",
+			Budget{Total: 120_000, Shares: 3},
+			[]int{6_000, 2_000, 4_000},
+			Evaluation{
+				Budget:    Budget{Total: 120_000, Shares: 3},
+				Offers:    3,
+				Sum:       144_000,
+				Coverage:  120,
+				Shortfall: 0,
+				Average:   4_000,
+				Median:    4_000,
+			},
+		},
+		{
+			"even number of offers",
+			Budget{Total: 120_000, Shares: 2},
+			[]int{5_000, 2_000},
+			Evaluation{
+				Budget:    Budget{Total: 120_000, Shares: 2},
+				Offers:    2,
+				Sum:       84_000,
+				Coverage:  70,
+				Shortfall: 36_000,
+				Average:   3_500,
+				Median:    3_500,
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := evaluate(tt.budget, tt.offers, 12)
+			if got != tt.expect {
+				t.Errorf("got %+v, expected %+v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestGuide(t *testing.T) {
+	c := DefaultConfig()
+	c.MinOffer = 4_500
+
+	got := guide(1_200_000, 20, c)
+	expect := Guide{Shares: 20, Value: 5_000, Min: 4_500, Max: 6_000, MinOffer: 4_500}
+	if got != expect {
+		t.Errorf("got %+v, expected %+v", got, expect)
+	}
+
+	if got := guide(1_200_000, 0, c); got.Value != 0 {
+		t.Errorf("guide without shares is %d, expected 0", got.Value)
+	}
+}
+
+func TestFormatEuro(t *testing.T) {
+	for cent, expect := range map[int]string{
+		0:          "0,00 €",
+		5:          "0,05 €",
+		12345:      "123,45 €",
+		123456789:  "1.234.567,89 €",
+		-100000000: "-1.000.000,00 €",
+	} {
+		if got := formatEuro(cent); got != expect {
+			t.Errorf("formatEuro(%d) = %q, expected %q", cent, got, expect)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package server
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 )
 
 const (
@@ -17,6 +19,12 @@ func getEvent(eventType string) Event {
 	case "delete":
 		return &eventDelete{}
 
+	case "import":
+		return &eventImport{}
+
+	case "token":
+		return &eventToken{}
+
 	case "state":
 		return &eventServiceState{}
 
@@ -26,6 +34,27 @@ func getEvent(eventType string) Event {
 	case "offer-clear":
 		return &eventOfferClear{}
 
+	case "round-start":
+		return &eventRoundStart{}
+
+	case "budget":
+		return &eventBudget{}
+
+	case "schedule":
+		return &eventSchedule{}
+
+	case "schedule-delete":
+		return &eventScheduleDelete{}
+
+	case "verteilstelle":
+		return &eventVerteilstelle{}
+
+	case "verteilstelle-delete":
+		return &eventVerteilstelleDelete{}
+
+	case "counters":
+		return &eventCounters{}
+
 	default:
 		return nil
 	}
@@ -41,14 +70,29 @@ type Event interface {
 type eventUpdate struct {
 	ID      string          `json:"id"`
 	Payload json.RawMessage `json:"payload"`
+
+	// Token is the access token of a new bieter.
+	Token string `json:"token,omitempty"`
+
+	data    bieterData
 	create  bool
 	asAdmin bool
 }
 
 func newEventCreate(id string, payload json.RawMessage, asAdmin bool) (eventUpdate, error) {
 	e, err := newEventUpdate(id, payload, asAdmin)
+	if err != nil {
+		return eventUpdate{}, err
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return eventUpdate{}, fmt.Errorf("creating token: %w", err)
+	}
+
 	e.create = true
-	return e, err
+	e.Token = token
+	return e, nil
 }
 
 func newEventUpdate(id string, payload json.RawMessage, asAdmin bool) (eventUpdate, error) {
@@ -60,9 +104,15 @@ func newEventUpdate(id string, payload json.RawMessage, asAdmin bool) (eventUpda
 		return eventUpdate{}, validationError{"Ungültige Daten übergeben"}
 	}
 
+	data, err := decodeBieterData(payload)
+	if err != nil {
+		return eventUpdate{}, err
+	}
+
 	e := eventUpdate{
 		ID:      id,
 		Payload: payload,
+		data:    data,
 		create:  false,
 		asAdmin: asAdmin,
 	}
@@ -79,26 +129,42 @@ func (e eventUpdate) Name() string {
 }
 
 func (e eventUpdate) validate(db *Database) error {
-	if !e.asAdmin && db.state != stateRegistration {
+	if !e.asAdmin && !db.state.bieterEditable() {
 		return validationError{"invalid state"}
 	}
 
-	_, exist := db.bieter[e.ID]
-	if e.create {
-		if exist {
-			return errIDExists
-		}
-		return nil
+	old, exist := db.bieter[e.ID]
+	if e.create && exist {
+		return errIDExists
 	}
 
-	if !exist {
+	if !e.create && !exist {
 		return validationError{fmt.Sprintf("Bieter %q does not exist", e.ID)}
 	}
+
+	// Only check the verteilstelle, if it is changed. Bieters keep their
+	// verteilstelle, when it gets full.
+	id := int(e.data.Verteilstelle)
+	if id == 0 || (exist && payloadVerteilstelle(old) == id) {
+		return nil
+	}
+
+	v, ok := db.verteilstellen[id]
+	if !ok {
+		return fieldErrors{{"verteilstelle", "Unbekannte Verteilstelle"}}
+	}
+
+	if !e.asAdmin && v.Capacity > 0 && db.verteilstelleMembers(id) >= v.Capacity {
+		return fieldErrors{{"verteilstelle", fmt.Sprintf("Die Verteilstelle %s ist voll", v.Name)}}
+	}
 	return nil
 }
 
 func (e eventUpdate) execute(db *Database) error {
 	db.bieter[e.ID] = e.Payload
+	if e.Token != "" {
+		db.setToken(e.ID, e.Token)
+	}
 	return nil
 }
 
@@ -119,8 +185,15 @@ func (e eventDelete) Name() string {
 	return "delete"
 }
 
+// validate allows an admin to delete a bieter until the offers are final. An
+// admin can still update the data of a bieter after that, for example to fix
+// the IBAN.
 func (e eventDelete) validate(db *Database) error {
-	if !e.asAdmin && db.state != stateRegistration {
+	if db.state.offerFinal() {
+		return validationError{"Nach dem Abschluss der Gebote kann kein Bieter gelöscht werden"}
+	}
+
+	if !e.asAdmin && !db.state.bieterEditable() {
 		return validationError{"invalid state"}
 	}
 	return nil
@@ -128,18 +201,99 @@ func (e eventDelete) validate(db *Database) error {
 
 func (e eventDelete) execute(db *Database) error {
 	delete(db.bieter, e.ID)
+	delete(db.offer, e.ID)
+	db.removeToken(e.ID)
+	return nil
+}
+
+// eventImport creates many bieters at once. Either all or none of them are
+// created.
+type eventImport struct {
+	Bieter []eventUpdate `json:"bieter"`
+}
+
+func newEventImport(bieter []eventUpdate) eventImport {
+	return eventImport{Bieter: bieter}
+}
+
+func (e eventImport) String() string {
+	return fmt.Sprintf("Importing %d bieters", len(e.Bieter))
+}
+
+func (e eventImport) Name() string {
+	return "import"
+}
+
+func (e eventImport) validate(db *Database) error {
+	ids := make(map[string]bool, len(e.Bieter))
+	for _, b := range e.Bieter {
+		if ids[b.ID] {
+			return errIDExists
+		}
+		ids[b.ID] = true
+
+		if err := b.validate(db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e eventImport) execute(db *Database) error {
+	for _, b := range e.Bieter {
+		if err := b.execute(db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type eventToken struct {
+	ID    string `json:"id"`
+	Token string `json:"token"`
+}
+
+func newEventToken(id string) (eventToken, error) {
+	token, err := newToken()
+	if err != nil {
+		return eventToken{}, err
+	}
+	return eventToken{ID: id, Token: token}, nil
+}
+
+func (e eventToken) String() string {
+	return fmt.Sprintf("New token for bieter %q", e.ID)
+}
+
+func (e eventToken) Name() string {
+	return "token"
+}
+
+func (e eventToken) validate(db *Database) error {
+	if _, ok := db.bieter[e.ID]; !ok {
+		return validationError{fmt.Sprintf("Bieter %q does not exist", e.ID)}
+	}
+	return nil
+}
+
+func (e eventToken) execute(db *Database) error {
+	db.setToken(e.ID, e.Token)
 	return nil
 }
 
 type eventServiceState struct {
 	NewState ServiceState `json:"state"`
+
+	// Schedule is the id of the scheduled transition, that created this
+	// event.
+	Schedule int `json:"schedule,omitempty"`
 }
 
 func newEventStatus(newState ServiceState) (eventServiceState, error) {
-	if int(newState) < 1 || int(newState) > 3 {
-		return eventServiceState{}, validationError{fmt.Sprintf("Ungültiger State mit nummer %q", newState)}
+	if !newState.valid() {
+		return eventServiceState{}, validationError{fmt.Sprintf("Ungültiger State mit nummer %d", int(newState))}
 	}
-	return eventServiceState{newState}, nil
+	return eventServiceState{NewState: newState}, nil
 }
 
 func (e eventServiceState) String() string {
@@ -151,11 +305,17 @@ func (e eventServiceState) Name() string {
 }
 
 func (e eventServiceState) validate(db *Database) error {
+	if e.Schedule != 0 {
+		if _, ok := db.schedule[e.Schedule]; !ok {
+			return validationError{fmt.Sprintf("Zeitplan %d existiert nicht", e.Schedule)}
+		}
+	}
 	return nil
 }
 
 func (e eventServiceState) execute(db *Database) error {
 	db.state = e.NewState
+	delete(db.schedule, e.Schedule)
 	return nil
 }
 
@@ -165,9 +325,17 @@ type eventOffer struct {
 	asAdmin bool
 }
 
-func newEventOffer(id string, offer int, asAdmin bool) (eventOffer, error) {
-	if int(offer) < lowestOffer {
-		return eventOffer{}, validationError{fmt.Sprintf("Das Gebot muss mindestens %d sein, nicht %q", lowestOffer, offer)}
+// newEventOffer creates an offer event.
+//
+// minOffer is the lowest offer, a bieter can make. An admin can set offers
+// down to lowestOffer.
+func newEventOffer(id string, offer int, minOffer int, asAdmin bool) (eventOffer, error) {
+	if offer < lowestOffer {
+		return eventOffer{}, validationError{fmt.Sprintf("Das Gebot muss mindestens %d sein, nicht %d", lowestOffer, offer)}
+	}
+
+	if !asAdmin && offer < minOffer {
+		return eventOffer{}, validationError{fmt.Sprintf("Das Gebot muss mindestens %s betragen", formatEuro(minOffer))}
 	}
 	return eventOffer{id, offer, asAdmin}, nil
 }
@@ -181,7 +349,11 @@ func (e eventOffer) Name() string {
 }
 
 func (e eventOffer) validate(db *Database) error {
-	if !e.asAdmin && db.state != stateOffer {
+	if db.state.offerFinal() {
+		return validationError{"Die Gebote sind abgeschlossen"}
+	}
+
+	if !e.asAdmin && !db.state.offerEditable() {
 		return validationError{"invalid state"}
 	}
 	if _, exist := db.bieter[e.ID]; !exist {
@@ -210,6 +382,9 @@ func (e eventOfferClear) Name() string {
 }
 
 func (e eventOfferClear) validate(db *Database) error {
+	if db.state.offerFinal() {
+		return validationError{"Die Gebote sind abgeschlossen"}
+	}
 	return nil
 }
 
@@ -218,6 +393,244 @@ func (e eventOfferClear) execute(db *Database) error {
 	return nil
 }
 
+type eventRoundStart struct {
+	Round int `json:"round"`
+}
+
+func newEventRoundStart(round int) eventRoundStart {
+	return eventRoundStart{round}
+}
+
+func (e eventRoundStart) String() string {
+	return fmt.Sprintf("Start round %d", e.Round)
+}
+
+func (e eventRoundStart) Name() string {
+	return "round-start"
+}
+
+func (e eventRoundStart) validate(db *Database) error {
+	if db.state.offerFinal() {
+		return validationError{"Die Gebote sind abgeschlossen"}
+	}
+
+	if e.Round != db.round+1 {
+		return validationError{fmt.Sprintf("Runde %d kann nicht gestartet werden. Aktuelle Runde ist %d", e.Round, db.round)}
+	}
+	return nil
+}
+
+func (e eventRoundStart) execute(db *Database) error {
+	db.rounds = append(db.rounds, db.offer)
+	db.offer = make(map[string]int)
+	db.round = e.Round
+	return nil
+}
+
+type eventBudget struct {
+	Budget Budget `json:"budget"`
+}
+
+func newEventBudget(budget Budget) (eventBudget, error) {
+	if budget.Total < 0 {
+		return eventBudget{}, validationError{"Das Budget darf nicht negativ sein"}
+	}
+
+	if budget.Shares < 0 {
+		return eventBudget{}, validationError{"Die Anzahl der Anteile darf nicht negativ sein"}
+	}
+	return eventBudget{budget}, nil
+}
+
+func (e eventBudget) String() string {
+	return fmt.Sprintf("Set budget to %d cent for %d shares", e.Budget.Total, e.Budget.Shares)
+}
+
+func (e eventBudget) Name() string {
+	return "budget"
+}
+
+func (e eventBudget) validate(db *Database) error {
+	return nil
+}
+
+func (e eventBudget) execute(db *Database) error {
+	db.budget = e.Budget
+	return nil
+}
+
+type eventSchedule struct {
+	ScheduledState
+}
+
+func newEventSchedule(id int, state ServiceState, at time.Time) (eventSchedule, error) {
+	if _, err := newEventStatus(state); err != nil {
+		return eventSchedule{}, err
+	}
+
+	if at.IsZero() {
+		return eventSchedule{}, validationError{"Kein Zeitpunkt angegeben"}
+	}
+
+	return eventSchedule{ScheduledState{ID: id, State: state, At: at}}, nil
+}
+
+func (e eventSchedule) String() string {
+	return fmt.Sprintf("Schedule state %q at %s", e.State.String(), e.At)
+}
+
+func (e eventSchedule) Name() string {
+	return "schedule"
+}
+
+func (e eventSchedule) validate(db *Database) error {
+	if _, exist := db.schedule[e.ID]; exist {
+		return errScheduleIDExists
+	}
+	return nil
+}
+
+func (e eventSchedule) execute(db *Database) error {
+	db.schedule[e.ID] = e.ScheduledState
+	if e.ID > db.lastScheduleID {
+		db.lastScheduleID = e.ID
+	}
+	return nil
+}
+
+type eventScheduleDelete struct {
+	ID int `json:"id"`
+}
+
+func newEventScheduleDelete(id int) eventScheduleDelete {
+	return eventScheduleDelete{id}
+}
+
+func (e eventScheduleDelete) String() string {
+	return fmt.Sprintf("Delete scheduled transition %d", e.ID)
+}
+
+func (e eventScheduleDelete) Name() string {
+	return "schedule-delete"
+}
+
+func (e eventScheduleDelete) validate(db *Database) error {
+	if _, exist := db.schedule[e.ID]; !exist {
+		return validationError{fmt.Sprintf("Zeitplan %d existiert nicht", e.ID)}
+	}
+	return nil
+}
+
+func (e eventScheduleDelete) execute(db *Database) error {
+	delete(db.schedule, e.ID)
+	return nil
+}
+
+type eventVerteilstelle struct {
+	Verteilstelle
+	create bool
+}
+
+func newEventVerteilstelle(v Verteilstelle, create bool) (eventVerteilstelle, error) {
+	if strings.TrimSpace(v.Name) == "" {
+		return eventVerteilstelle{}, validationError{"Der Name der Verteilstelle fehlt"}
+	}
+
+	if v.Capacity < 0 {
+		return eventVerteilstelle{}, validationError{"Die Kapazität darf nicht negativ sein"}
+	}
+	return eventVerteilstelle{v, create}, nil
+}
+
+func (e eventVerteilstelle) String() string {
+	return fmt.Sprintf("Set verteilstelle %d to %q", e.ID, e.Verteilstelle.Name)
+}
+
+func (e eventVerteilstelle) Name() string {
+	return "verteilstelle"
+}
+
+func (e eventVerteilstelle) validate(db *Database) error {
+	_, exist := db.verteilstellen[e.ID]
+	if e.create && exist {
+		return errVerteilstelleIDExists
+	}
+
+	if !e.create && !exist {
+		return validationError{fmt.Sprintf("Verteilstelle %d existiert nicht", e.ID)}
+	}
+	return nil
+}
+
+func (e eventVerteilstelle) execute(db *Database) error {
+	db.verteilstellen[e.ID] = e.Verteilstelle
+	if e.ID > db.lastVerteilstelleID {
+		db.lastVerteilstelleID = e.ID
+	}
+	return nil
+}
+
+type eventVerteilstelleDelete struct {
+	ID int `json:"id"`
+}
+
+func newEventVerteilstelleDelete(id int) eventVerteilstelleDelete {
+	return eventVerteilstelleDelete{id}
+}
+
+func (e eventVerteilstelleDelete) String() string {
+	return fmt.Sprintf("Delete verteilstelle %d", e.ID)
+}
+
+func (e eventVerteilstelleDelete) Name() string {
+	return "verteilstelle-delete"
+}
+
+func (e eventVerteilstelleDelete) validate(db *Database) error {
+	if _, exist := db.verteilstellen[e.ID]; !exist {
+		return validationError{fmt.Sprintf("Verteilstelle %d existiert nicht", e.ID)}
+	}
+
+	if members := db.verteilstelleMembers(e.ID); members > 0 {
+		return validationError{fmt.Sprintf("Die Verteilstelle wird noch von %d Bietern verwendet", members)}
+	}
+	return nil
+}
+
+func (e eventVerteilstelleDelete) execute(db *Database) error {
+	delete(db.verteilstellen, e.ID)
+	return nil
+}
+
+// eventCounters sets the last used ids of schedules and verteilstellen. It is
+// used by the compaction, so deleted ids are not used again.
+type eventCounters struct {
+	LastSchedule      int `json:"last_schedule,omitempty"`
+	LastVerteilstelle int `json:"last_verteilstelle,omitempty"`
+}
+
+func (e eventCounters) String() string {
+	return fmt.Sprintf("Set last schedule id to %d and last verteilstelle id to %d", e.LastSchedule, e.LastVerteilstelle)
+}
+
+func (e eventCounters) Name() string {
+	return "counters"
+}
+
+func (e eventCounters) validate(db *Database) error {
+	return nil
+}
+
+func (e eventCounters) execute(db *Database) error {
+	if e.LastSchedule > db.lastScheduleID {
+		db.lastScheduleID = e.LastSchedule
+	}
+	if e.LastVerteilstelle > db.lastVerteilstelleID {
+		db.lastVerteilstelleID = e.LastVerteilstelle
+	}
+	return nil
+}
+
 type validationError struct {
 	msg string
 }
@@ -230,4 +643,26 @@ func (e validationError) forClient() string {
 	return "Ungültige Daten: " + e.msg
 }
 
+// forbiddenError is returned, if the user does not have the permission for an
+// action.
+type forbiddenError struct{}
+
+func (e forbiddenError) Error() string {
+	return "not allowed"
+}
+
+func (e forbiddenError) forClient() string {
+	return "not allowed"
+}
+
+func (e forbiddenError) httpStatus() int {
+	return 403
+}
+
+var errForbidden = forbiddenError{}
+
 var errIDExists = validationError{"Bieter ID existiert bereits"}
+
+var errScheduleIDExists = validationError{"Zeitplan ID existiert bereits"}
+
+var errVerteilstelleIDExists = validationError{"Verteilstelle ID existiert bereits"}
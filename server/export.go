@@ -0,0 +1,235 @@
+package server
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// exportRow is one bieter in the export.
+type exportRow struct {
+	id            string
+	data          bieterData
+	offer         int
+	payments      int
+	verteilstelle string
+}
+
+// exportColumn is a column of the export.
+//
+// Amount columns have the function cent, all other columns the function
+// text.
+type exportColumn struct {
+	key   string
+	title string
+	text  func(r exportRow) string
+	cent  func(r exportRow) int
+}
+
+// exportColumns are all columns, that can be exported.
+var exportColumns = []exportColumn{
+	{key: "id", title: "Bieternummer", text: func(r exportRow) string { return r.id }},
+	{key: "name", title: "Name", text: func(r exportRow) string { return r.data.Name }},
+	{key: "teilpartner", title: "Teilpartner", text: func(r exportRow) string { return r.data.Teilpartner }},
+	{key: "mail", title: "E-Mail", text: func(r exportRow) string { return r.data.Mail }},
+	{key: "teilpartnerMail", title: "E-Mail Teilpartner", text: func(r exportRow) string { return r.data.TeilpartnerMail }},
+	{key: "mitglied", title: "Mitglied", text: func(r exportRow) string { return r.data.Mitglied }},
+	{key: "adresse", title: "Adresse", text: func(r exportRow) string { return r.data.Adresse }},
+	{key: "verteilstelle", title: "Verteilstelle", text: func(r exportRow) string { return r.verteilstelle }},
+	{key: "kontoinhaber", title: "Kontoinhaber", text: func(r exportRow) string { return r.data.Kontoinhaber }},
+	{key: "iban", title: "IBAN", text: func(r exportRow) string { return r.data.IBAN }},
+	{key: "abbuchung", title: "Abbuchung", text: func(r exportRow) string { return r.data.Abbuchung.String() }},
+	{key: "offer", title: "Gebot monatlich", cent: func(r exportRow) int { return r.offer }},
+	{key: "yearly", title: "Betrag Saison", cent: func(r exportRow) int { return r.offer * r.payments }},
+}
+
+// exportTable is the result of an export.
+type exportTable struct {
+	columns []exportColumn
+	rows    []exportRow
+}
+
+// Export returns the table of all bieters.
+//
+// columns are the keys of the columns to export. If it is empty, all columns
+// are exported. sortBy is the key of the column to sort by. With a leading
+// "-" the order is descending. Without sortBy, the table is sorted by id.
+func (db *Database) Export(c Config, columns []string, sortBy string) (exportTable, error) {
+	if sortBy == "" {
+		sortBy = "id"
+	}
+
+	var table exportTable
+	if len(columns) == 0 {
+		table.columns = exportColumns
+	}
+
+	for _, key := range columns {
+		column, ok := findExportColumn(key)
+		if !ok {
+			return exportTable{}, validationError{fmt.Sprintf("Unbekannte Spalte %q", key)}
+		}
+		table.columns = append(table.columns, column)
+	}
+
+	descending := strings.HasPrefix(sortBy, "-")
+	sortColumn, ok := findExportColumn(strings.TrimPrefix(sortBy, "-"))
+	if !ok {
+		return exportTable{}, validationError{fmt.Sprintf("Unbekannte Spalte %q zum Sortieren", sortBy)}
+	}
+
+	db.RLock()
+	for id, payload := range db.bieter {
+		r := exportRow{
+			id:       id,
+			offer:    db.offer[id],
+			payments: c.Season.payments(),
+		}
+
+		// Old payloads could be invalid. Export as much as possible.
+		json.Unmarshal(payload, &r.data)
+
+		if v, ok := db.verteilstellen[int(r.data.Verteilstelle)]; ok {
+			r.verteilstelle = v.Name
+		}
+		table.rows = append(table.rows, r)
+	}
+	db.RUnlock()
+
+	sort.Slice(table.rows, func(i, j int) bool {
+		a, b := table.rows[i], table.rows[j]
+		if descending {
+			a, b = b, a
+		}
+
+		if sortColumn.cent != nil {
+			if sortColumn.cent(a) != sortColumn.cent(b) {
+				return sortColumn.cent(a) < sortColumn.cent(b)
+			}
+		} else if ta, tb := strings.ToLower(sortColumn.text(a)), strings.ToLower(sortColumn.text(b)); ta != tb {
+			return ta < tb
+		}
+		return a.id < b.id
+	})
+
+	return table, nil
+}
+
+func findExportColumn(key string) (exportColumn, bool) {
+	for _, column := range exportColumns {
+		if column.key == key {
+			return column, true
+		}
+	}
+	return exportColumn{}, false
+}
+
+// csvFormulaPrefixes are the first characters, that a spreadsheet program reads
+// as the start of a formula. Tab and carriage return are included, because
+// some programs skip them and read the formula after them.
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// csvText escapes a text cell, so a spreadsheet program does not run it as a
+// formula. A cell that starts like a formula gets the prefix '.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune(csvFormulaPrefixes, rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// writeCSV writes the table as csv, that can be opened by a german
+// spreadsheet program.
+//
+// The file starts with a byte order mark and uses semicolons. Amounts are
+// formatted like 1.234,56.
+func (t exportTable) writeCSV(w io.Writer) error {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return fmt.Errorf("writing byte order mark: %w", err)
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = ';'
+
+	header := make([]string, len(t.columns))
+	for i, column := range t.columns {
+		header[i] = column.title
+	}
+	if err := cw.Write(header); err != nil {
+		return fmt.Errorf("writing header: %w", err)
+	}
+
+	for _, r := range t.rows {
+		record := make([]string, len(t.columns))
+		for i, column := range t.columns {
+			if column.cent != nil {
+				record[i] = formatDecimal(column.cent(r))
+				continue
+			}
+			record[i] = csvText(column.text(r))
+		}
+
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("writing row: %w", err)
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeXLSX writes the table as excel file.
+//
+// Amounts are saved as numbers in euro with a currency format.
+func (t exportTable) writeXLSX(w io.Writer) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	const sheet = "Bieter"
+	if err := f.SetSheetName("Sheet1", sheet); err != nil {
+		return fmt.Errorf("naming sheet: %w", err)
+	}
+
+	euroFormat := `#,##0.00 "€"`
+	amountStyle, err := f.NewStyle(&excelize.Style{CustomNumFmt: &euroFormat})
+	if err != nil {
+		return fmt.Errorf("creating amount style: %w", err)
+	}
+
+	headerStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return fmt.Errorf("creating header style: %w", err)
+	}
+
+	for i, column := range t.columns {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+		f.SetCellStr(sheet, cell, column.title)
+		f.SetCellStyle(sheet, cell, cell, headerStyle)
+	}
+
+	for row, r := range t.rows {
+		for i, column := range t.columns {
+			cell, _ := excelize.CoordinatesToCellName(i+1, row+2)
+			if column.cent != nil {
+				f.SetCellFloat(sheet, cell, float64(column.cent(r))/100, 2, 64)
+				f.SetCellStyle(sheet, cell, cell, amountStyle)
+				continue
+			}
+			f.SetCellStr(sheet, cell, column.text(r))
+		}
+	}
+
+	if err := f.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return fmt.Errorf("freezing header: %w", err)
+	}
+
+	if _, err := f.WriteTo(w); err != nil {
+		return fmt.Errorf("writing xlsx: %w", err)
+	}
+	return nil
+}
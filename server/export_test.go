@@ -0,0 +1,131 @@
+package server
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const exportTestEvents = `
+{"type":"verteilstelle","payload":{"id":1,"name":"Villingen"}}
+{"type":"update","payload":{"id":"1","payload":{"name":"Hugo","verteilstelle":1}}}
+{"type":"update","payload":{"id":"2","payload":{"name":"erika","abbuchung":1}}}
+{"type":"update","payload":{"id":"3","payload":{"name":"Anna; Bert"}}}
+{"type":"offer","payload":{"id":"1","offer":123456}}
+{"type":"offer","payload":{"id":"2","offer":5000}}
+`
+
+func TestExportCSV(t *testing.T) {
+	db, err := loadDatabase(strings.NewReader(exportTestEvents), ReplayLimit{})
+	if err != nil {
+		t.Fatalf("loadDatabase: %v", err)
+	}
+
+	table, err := db.Export(DefaultConfig(), []string{"name", "verteilstelle", "abbuchung", "offer", "yearly"}, "-offer")
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := table.writeCSV(&buf); err != nil {
+		t.Fatalf("writeCSV: %v", err)
+	}
+
+	expect := "\ufeff" + `Name;Verteilstelle;Abbuchung;Gebot monatlich;Betrag Saison
+Hugo;Villingen;Monatlich;1.234,56;14.814,72
+erika;;Jährlich;50,00;600,00
+"Anna; Bert";;Monatlich;0,00;0,00
+`
+	if got := buf.String(); got != expect {
+		t.Errorf("got\n%s\nexpected\n%s", got, expect)
+	}
+
+	table, err = db.Export(DefaultConfig(), []string{"id"}, "name")
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if got := []string{table.rows[0].id, table.rows[1].id, table.rows[2].id}; strings.Join(got, ",") != "3,2,1" {
+		t.Errorf("sorted by name is %v, expected 3,2,1", got)
+	}
+
+	if _, err := db.Export(DefaultConfig(), []string{"unknown"}, ""); err == nil {
+		t.Errorf("got no error for an unknown column")
+	}
+}
+
+func TestExportCSVFormula(t *testing.T) {
+	db, err := loadDatabase(strings.NewReader(`
+{"type":"update","payload":{"id":"1","payload":{"name":"=HYPERLINK(\"http://example.com\")","adresse":"+49 7721","mail":"@hugo"}}}
+{"type":"update","payload":{"id":"2","payload":{"name":"-1","adresse":"Haupt-Straße 1","mail":"a=b@example.com"}}}
+{"type":"update","payload":{"id":"3","payload":{"name":"\t=1+1","adresse":"\r=1+1"}}}
+`), ReplayLimit{})
+	if err != nil {
+		t.Fatalf("loadDatabase: %v", err)
+	}
+
+	table, err := db.Export(DefaultConfig(), []string{"id", "name", "adresse", "mail"}, "id")
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := table.writeCSV(&buf); err != nil {
+		t.Fatalf("writeCSV: %v", err)
+	}
+
+	expect := "\ufeff" + `Bieternummer;Name;Adresse;E-Mail
+1;"'=HYPERLINK(""http://example.com"")";'+49 7721;'@hugo
+2;'-1;Haupt-Straße 1;a=b@example.com
+` + "3;'\t=1+1;\"'\r=1+1\";\n"
+	if got := buf.String(); got != expect {
+		t.Errorf("got\n%s\nexpected\n%s", got, expect)
+	}
+
+	for _, tt := range []struct {
+		value  string
+		expect string
+	}{
+		{"'=1+1", "=1+1"},
+		{"'\t=1+1", "\t=1+1"},
+		{"'\r=1+1", "\r=1+1"},
+		{"'Hugo", "'Hugo"},
+	} {
+		if got := csvUnescape(tt.value); got != tt.expect {
+			t.Errorf("csvUnescape(%q) returned %q, expected %q", tt.value, got, tt.expect)
+		}
+	}
+}
+
+func TestExportXLSX(t *testing.T) {
+	db, err := loadDatabase(strings.NewReader(exportTestEvents), ReplayLimit{})
+	if err != nil {
+		t.Fatalf("loadDatabase: %v", err)
+	}
+
+	table, err := db.Export(DefaultConfig(), []string{"name", "offer"}, "id")
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := table.writeXLSX(&buf); err != nil {
+		t.Fatalf("writeXLSX: %v", err)
+	}
+
+	f, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatalf("reading xlsx: %v", err)
+	}
+	defer f.Close()
+
+	rows, err := f.GetRows("Bieter", excelize.Options{RawCellValue: true})
+	if err != nil {
+		t.Fatalf("GetRows: %v", err)
+	}
+
+	if len(rows) != 4 || rows[1][0] != "Hugo" || rows[1][1] != "1234.56" {
+		t.Errorf("got rows %v", rows)
+	}
+}
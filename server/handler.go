@@ -1,6 +1,9 @@
 package server
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -10,7 +13,10 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -20,7 +26,7 @@ const (
 	pathPrefixStatic = "/static"
 )
 
-func registerHandlers(router *mux.Router, config Config, db *Database, defaultFiles DefaultFiles) {
+func registerHandlers(router *mux.Router, config Config, db *Database, archive map[string]*Database, sessions *sessionStore, contract *ContractTemplate, defaultFiles DefaultFiles) {
 	fileSystem := MultiFS{
 		fs: []fs.FS{
 			os.DirFS("./static"),
@@ -28,27 +34,125 @@ func registerHandlers(router *mux.Router, config Config, db *Database, defaultFi
 		},
 	}
 
+	limiter := newFailureLimiter(config.RateLimit)
+
 	router.Use(loggingMiddleware)
+	router.Use(requestMiddleware(config))
+	router.Use(limiter.middleware(config))
+	router.Use(sessions.middleware)
 
 	handleElmJS(router, defaultFiles.Elm)
 	handleIndex(router, defaultFiles.Index)
 
-	handleBieter(router, db, config, fileSystem)
+	handleLogin(router, config, sessions, limiter)
+
+	handleBieter(router, db, config, limiter, contract, fileSystem)
 	handleBieterCreate(router, db, config)
 	handleBieterList(router, db, config)
+	handleToken(router, db, config)
+	handleContracts(router, db, config, contract, fileSystem)
 
 	handleState(router, db, config)
-	handleSetOffer(router, db, config)
-	handleClearOffer(router, db, config)
+	handleSchedule(router, db, config)
+	handleSetOffer(router, db, config, limiter)
+	handleRound(router, db, config)
+	handleBudget(router, db, config)
+	handleEvaluation(router, db, config)
+	handleGuide(router, db, config)
+	handleSEPAExport(router, db, config)
+	handleVerteilstelle(router, db, config)
+	handleExport(router, db, config)
+	handleImport(router, db, config)
+	handleArchive(router, archive, config, limiter)
+	handleHistory(router, db, config, limiter)
 
 	handleStatic(router, fileSystem)
 }
 
+// handleLogin starts and ends admin sessions.
+//
+// POST /api/login with {"name": "...", "password": "..."} returns the session
+// token and sets it as cookie. Without a name, the admin password is used. POST /api/logout ends the session, with ?all=1 all sessions.
+func handleLogin(router *mux.Router, config Config, sessions *sessionStore, limiter *failureLimiter) {
+	router.Path(pathPrefixAPI + "/login").Methods("POST").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var content struct {
+			Name     string `json:"name"`
+			Password string `json:"password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&content); err != nil {
+			handleError(w, clientError{msg: "Ungültige Daten übergeben"})
+			return
+		}
+
+		user, ok := config.authenticate(content.Name, content.Password)
+		if !ok {
+			limiter.fail(clientIP(r, config.RateLimit), "admin login")
+			handleError(w, clientError{msg: "Name oder Passwort ist falsch", status: 401})
+			return
+		}
+
+		token, expires, err := sessions.create(user)
+		if err != nil {
+			handleError(w, fmt.Errorf("creating session: %w", err))
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     sessionCookie,
+			Value:    token,
+			Path:     "/",
+			Expires:  expires,
+			HttpOnly: true,
+			Secure:   strings.HasPrefix(config.Domain, "https://"),
+			SameSite: http.SameSiteStrictMode,
+		})
+
+		session := struct {
+			Token   string    `json:"token"`
+			Expires time.Time `json:"expires"`
+			Name    string    `json:"name"`
+			Role    Role      `json:"role"`
+		}{token, expires, user.Name, user.Role}
+
+		if err := json.NewEncoder(w).Encode(session); err != nil {
+			handleError(w, fmt.Errorf("encoding session: %w", err))
+		}
+	})
+
+	router.Path(pathPrefixAPI + "/logout").Methods("POST").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("all") == "1" {
+			if !can(r, permWrite) {
+				handleError(w, errForbidden)
+				return
+			}
+			sessions.revokeAll()
+		}
+
+		sessions.revoke(sessionToken(r))
+		http.SetCookie(w, &http.Cookie{
+			Name:     sessionCookie,
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: true,
+		})
+	})
+}
+
 // ViewBieter is the bieter data returned to the client
 type ViewBieter struct {
 	ID      string          `json:"id"`
 	Payload json.RawMessage `json:"payload"`
 	Offer   int             `json:"offer"`
+
+	// Token is the secret access token of the bieter.
+	Token string `json:"token,omitempty"`
+
+	// Rounds are the offers of the finished rounds.
+	Rounds []RoundOffer `json:"rounds,omitempty"`
+
+	// Problems are validation errors of the payload. It is only set in the
+	// admin list.
+	Problems []string `json:"problems,omitempty"`
 }
 
 // handleIndex returns the index.html. It is returned from all urls exept /api
@@ -95,26 +199,42 @@ func handleElmJS(router *mux.Router, defaultContent []byte) {
 	router.Path("/elm.js").HandlerFunc(handler)
 }
 
-// handleBieter handles request to /bieter/id. Get returns the bieter, put
-// updates it and delete deletes it
-func handleBieter(router *mux.Router, db *Database, config Config, filesystem fs.FS) {
+// handleBieter handles request to /bieter/token. Get returns the bieter, put
+// updates it and delete deletes it.
+//
+// Only the access token of the bieter grants access. An admin can also use the
+// id.
+func handleBieter(router *mux.Router, db *Database, config Config, limiter *failureLimiter, contract *ContractTemplate, filesystem fs.FS) {
 	path := pathPrefixAPI + "/bieter/{id}"
 
 	router.Path(path).Methods("DELETE").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		bieterID := mux.Vars(r)["id"]
-		_, exist := db.Bieter(bieterID)
-		if !exist {
-			handleError(w, clientError{msg: "Bieter existiert nicht", status: 404})
+		bieterID, ok := findBieter(w, r, db, config, limiter, permWrite)
+		if !ok {
 			return
 		}
 
-		if err := db.DeleteBieter(bieterID, isAdmin(r, config)); err != nil {
+		if err := db.DeleteBieter(bieterID, requestUser(r)); err != nil {
 			handleError(w, fmt.Errorf("deleting bieter %q: %w", bieterID, err))
 		}
 	})
 
 	router.Path(path).Methods("GET", "PUT").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		bieterID := mux.Vars(r)["id"]
+		perm := permRead
+		if r.Method == "PUT" {
+			perm = permWrite
+		}
+
+		bieterID, ok := findBieter(w, r, db, config, limiter, perm)
+		if !ok {
+			return
+		}
+
+		db, err := readDB(r, db)
+		if err != nil {
+			handleError(w, err)
+			return
+		}
+
 		payload, exist := db.Bieter(bieterID)
 		if !exist {
 			handleError(w, clientError{msg: "Bieter existiert nicht", status: 404})
@@ -124,7 +244,7 @@ func handleBieter(router *mux.Router, db *Database, config Config, filesystem fs
 		offer := db.Offer(bieterID)
 
 		if r.Method == "PUT" {
-			p, err := db.UpdateBieter(bieterID, r.Body, isAdmin(r, config))
+			p, err := db.UpdateBieter(bieterID, r.Body, requestUser(r))
 			if err != nil {
 				handleError(w, fmt.Errorf("update bieter: %w", err))
 				return
@@ -133,9 +253,11 @@ func handleBieter(router *mux.Router, db *Database, config Config, filesystem fs
 		}
 
 		bieter := ViewBieter{
-			bieterID,
-			payload,
-			offer,
+			ID:      bieterID,
+			Payload: payload,
+			Offer:   offer,
+			Token:   viewToken(r, db, bieterID, mux.Vars(r)["id"]),
+			Rounds:  db.OfferHistory(bieterID),
 		}
 
 		if err := json.NewEncoder(w).Encode(bieter); err != nil {
@@ -145,34 +267,44 @@ func handleBieter(router *mux.Router, db *Database, config Config, filesystem fs
 	})
 
 	router.Path(path + "/pdf").Methods("GET").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		bieterID := mux.Vars(r)["id"]
+		bieterID, ok := findBieter(w, r, db, config, limiter, permRead)
+		if !ok {
+			return
+		}
+
 		payload, exist := db.Bieter(bieterID)
 		if !exist {
 			handleError(w, clientError{msg: "Bieter existiert nicht", status: 404})
 			return
 		}
 
-		f, err := filesystem.Open("static/images/pdf_header_image.png")
-		if err != nil {
-			handleError(w, fmt.Errorf("open header image: %w", err))
+		if !can(r, permRead) && !db.State().contractAvailable() {
+			handleError(w, clientError{msg: "Der Vertrag kann noch nicht heruntergeladen werden", status: 403})
 			return
 		}
-		imgBytes, err := io.ReadAll(f)
+
+		headerImage, err := loadHeaderImage(filesystem)
 		if err != nil {
-			handleError(w, fmt.Errorf("reading header image: %w", err))
+			handleError(w, err)
 			return
 		}
 
-		headerImage := base64.StdEncoding.EncodeToString(imgBytes)
 		var data pdfData
-		if err := json.Unmarshal(payload, &data); err != nil {
+		if err := json.Unmarshal(payload, &data.bieterData); err != nil {
 			handleError(w, fmt.Errorf("decode bieter data: %w", err))
 			return
 		}
 
+		if err := data.validateComplete(); err != nil {
+			handleError(w, fmt.Errorf("bieter data for contract: %w", err))
+			return
+		}
+
 		data.offer = db.Offer(bieterID)
+		data.token = db.Token(bieterID)
+		data.verteilstelle, _ = db.Verteilstelle(int(data.Verteilstelle))
 
-		pdfile, err := Bietervertrag(config.Domain, bieterID, headerImage, data)
+		pdfile, err := Bietervertrag(config, contract, bieterID, headerImage, data)
 		if err != nil {
 			handleError(w, fmt.Errorf("creating pdf: %w", err))
 			return
@@ -181,6 +313,73 @@ func handleBieter(router *mux.Router, db *Database, config Config, filesystem fs
 	})
 }
 
+// handleContracts returns the contracts of all bieters.
+//
+// With the query parameter format=pdf, one merged pdf is returned. Otherwise a
+// zip file with one pdf for each bieter. The parameter verteilstelle limits
+// the contracts to one verteilstelle.
+func handleContracts(router *mux.Router, db *Database, config Config, contract *ContractTemplate, filesystem fs.FS) {
+	router.Path(pathPrefixAPI + "/contracts").Methods("GET").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !can(r, permExport) {
+			handleError(w, errForbidden)
+			return
+		}
+
+		var verteilstelleID int
+		if v := r.URL.Query().Get("verteilstelle"); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				handleError(w, clientError{msg: "Ungültige Verteilstelle"})
+				return
+			}
+			verteilstelleID = id
+		}
+
+		headerImage, err := loadHeaderImage(filesystem)
+		if err != nil {
+			handleError(w, err)
+			return
+		}
+
+		jobs, problems := db.contractJobs(verteilstelleID)
+
+		render := func(job contractJob) (*bytes.Buffer, error) {
+			return Bietervertrag(config, contract, job.bieterID, headerImage, job.data)
+		}
+
+		if r.URL.Query().Get("format") == "pdf" {
+			pdfile, err := BietervertragMerged(r.Context(), jobs, render)
+			if err != nil {
+				handleError(w, fmt.Errorf("creating pdf: %w", err))
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Header().Set("Content-Disposition", `attachment; filename="vertraege.pdf"`)
+			io.Copy(w, pdfile)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="vertraege.zip"`)
+		if err := writeContractsZIP(r.Context(), w, jobs, problems, render); err != nil {
+			// The response is already started, so the client gets a broken
+			// zip file.
+			log.Printf("Error: writing contracts: %v", err)
+		}
+	})
+}
+
+// loadHeaderImage returns the base64 encoded image for the header of the
+// contract.
+func loadHeaderImage(filesystem fs.FS) (string, error) {
+	imgBytes, err := fs.ReadFile(filesystem, "static/images/pdf_header_image.png")
+	if err != nil {
+		return "", fmt.Errorf("reading header image: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(imgBytes), nil
+}
+
 func handleBieterCreate(router *mux.Router, db *Database, config Config) {
 	router.Path(pathPrefixAPI + "/bieter").Methods("POST").HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
@@ -190,16 +389,16 @@ func handleBieterCreate(router *mux.Router, db *Database, config Config) {
 				return
 			}
 
-			bieterID, err := db.NewBieter(body, isAdmin(r, config))
+			bieterID, err := db.NewBieter(body, requestUser(r))
 			if err != nil {
 				handleError(w, fmt.Errorf("creating new bieter: %w", err))
 				return
 			}
 
 			bieter := ViewBieter{
-				bieterID,
-				body,
-				0,
+				ID:      bieterID,
+				Payload: body,
+				Token:   db.Token(bieterID),
 			}
 
 			if err := json.NewEncoder(w).Encode(bieter); err != nil {
@@ -211,24 +410,33 @@ func handleBieterCreate(router *mux.Router, db *Database, config Config) {
 }
 
 func handleBieterList(router *mux.Router, db *Database, config Config) {
-	if config.AdminPW == "" {
-		return
-	}
-
 	router.Path(pathPrefixAPI + "/bieter").Methods("GET").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		admin := isAdmin(r, config)
-		if !admin {
+		user := requestUser(r)
+		if !user.can(permRead) {
 			handleError(w, clientError{msg: "Passwort ist falsch", status: 401})
 			return
 		}
 
+		db, err := readDB(r, db)
+		if err != nil {
+			handleError(w, err)
+			return
+		}
+
 		var bieter []ViewBieter
 
 		for id, payload := range db.BieterList() {
+			if !user.seesBieter(payload) {
+				continue
+			}
+
 			bieter = append(bieter, ViewBieter{
-				ID:      id,
-				Payload: payload,
-				Offer:   db.Offer(id), // TODO: This has to be returned from db.BieterList!
+				ID:       id,
+				Payload:  payload,
+				Offer:    db.Offer(id), // TODO: This has to be returned from db.BieterList!
+				Token:    viewToken(r, db, id, ""),
+				Rounds:   db.OfferHistory(id),
+				Problems: payloadProblems(payload),
 			})
 
 		}
@@ -239,17 +447,37 @@ func handleBieterList(router *mux.Router, db *Database, config Config) {
 	})
 }
 
+// payloadProblems returns the validation errors of a saved payload. The
+// payload could be saved before it was validated.
+func payloadProblems(payload json.RawMessage) []string {
+	_, err := decodeBieterData(payload)
+	if err == nil {
+		return nil
+	}
+
+	var errs fieldErrors
+	if !errors.As(err, &errs) {
+		return []string{err.Error()}
+	}
+
+	problems := make([]string, len(errs))
+	for i, e := range errs {
+		problems[i] = fmt.Sprintf("%s: %s", e.Field, e.Msg)
+	}
+	return problems
+}
+
 // handleState gets or sets the service status.
 func handleState(router *mux.Router, db *Database, config Config) {
 	router.Path(pathPrefixAPI+"/state").Methods("GET", "PUT").
 		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if r.Method == "PUT" {
-				if !isAdmin(r, config) {
-					handleError(w, clientError{msg: "not allowed", status: 403})
+				if !can(r, permWrite) {
+					handleError(w, errForbidden)
 					return
 				}
 
-				if err := db.SetState(r.Body); err != nil {
+				if err := db.SetState(r.Body, requestUser(r)); err != nil {
 					handleError(w, fmt.Errorf("set state: %w", err))
 					return
 				}
@@ -257,11 +485,18 @@ func handleState(router *mux.Router, db *Database, config Config) {
 
 			s := db.State()
 			response := struct {
-				State int    `json:"state"`
-				Name  string `json:"state_name"`
+				State int             `json:"state"`
+				Name  string          `json:"state_name"`
+				Round int             `json:"round"`
+				Next  *viewTransition `json:"next,omitempty"`
 			}{
-				int(s),
-				s.String(),
+				State: int(s),
+				Name:  s.String(),
+				Round: db.Round(),
+			}
+
+			if next, ok := db.NextTransition(); ok {
+				response.Next = newViewTransition(next)
 			}
 
 			if err := json.NewEncoder(w).Encode(response); err != nil {
@@ -271,21 +506,574 @@ func handleState(router *mux.Router, db *Database, config Config) {
 		})
 }
 
-func handleClearOffer(router *mux.Router, db *Database, config Config) {
-	router.Path(pathPrefixAPI + "/offer").Methods("DELETE").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if err := db.ClearOffer(isAdmin(r, config)); err != nil {
-			handleError(w, fmt.Errorf("clear offers: %w", err))
+// handleRound starts a new bidding round. The offers of the current round are
+// archived.
+//
+// DELETE /api/offer is the old name of this handler.
+func handleRound(router *mux.Router, db *Database, config Config) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if err := db.StartRound(requestUser(r)); err != nil {
+			handleError(w, fmt.Errorf("start round: %w", err))
+			return
+		}
+
+		response := struct {
+			Round int `json:"round"`
+		}{
+			db.Round(),
+		}
+
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			handleError(w, fmt.Errorf("encoding round: %w", err))
+			return
+		}
+	}
+
+	router.Path(pathPrefixAPI + "/offer").Methods("DELETE").HandlerFunc(handler)
+	router.Path(pathPrefixAPI + "/round").Methods("POST").HandlerFunc(handler)
+}
+
+// viewTransition is a scheduled state transition returned to the client.
+type viewTransition struct {
+	ID    int       `json:"id"`
+	State int       `json:"state"`
+	Name  string    `json:"state_name"`
+	At    time.Time `json:"at"`
+
+	// Countdown is the number of seconds until the transition.
+	Countdown int `json:"countdown"`
+}
+
+func newViewTransition(s ScheduledState) *viewTransition {
+	countdown := int(time.Until(s.At).Seconds())
+	if countdown < 0 {
+		countdown = 0
+	}
+
+	return &viewTransition{
+		ID:        s.ID,
+		State:     int(s.State),
+		Name:      s.State.String(),
+		At:        s.At,
+		Countdown: countdown,
+	}
+}
+
+// handleSchedule lists, creates and deletes scheduled state transitions.
+func handleSchedule(router *mux.Router, db *Database, config Config) {
+	path := pathPrefixAPI + "/schedule"
+
+	router.Path(path).Methods("GET", "POST").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !can(r, permRead) {
+			handleError(w, errForbidden)
+			return
+		}
+
+		if r.Method == "POST" {
+			if _, err := db.AddSchedule(r.Body, requestUser(r)); err != nil {
+				handleError(w, fmt.Errorf("add schedule: %w", err))
+				return
+			}
+		}
+
+		schedule := []*viewTransition{}
+		for _, s := range db.Schedule() {
+			schedule = append(schedule, newViewTransition(s))
+		}
+
+		if err := json.NewEncoder(w).Encode(schedule); err != nil {
+			handleError(w, fmt.Errorf("encoding schedule: %w", err))
+			return
+		}
+	})
+
+	router.Path(path + "/{id:[0-9]+}").Methods("DELETE").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(mux.Vars(r)["id"])
+		if err := db.DeleteSchedule(id, requestUser(r)); err != nil {
+			handleError(w, fmt.Errorf("delete schedule: %w", err))
+			return
+		}
+	})
+}
+
+// handleBudget gets or sets the budget.
+func handleBudget(router *mux.Router, db *Database, config Config) {
+	router.Path(pathPrefixAPI+"/budget").Methods("GET", "PUT").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !can(r, permRead) {
+				handleError(w, errForbidden)
+				return
+			}
+
+			if r.Method == "PUT" {
+				if err := db.SetBudget(r.Body, requestUser(r)); err != nil {
+					handleError(w, fmt.Errorf("set budget: %w", err))
+					return
+				}
+			}
+
+			if err := json.NewEncoder(w).Encode(db.Budget()); err != nil {
+				handleError(w, fmt.Errorf("encoding budget: %w", err))
+				return
+			}
+		})
+}
+
+// handleEvaluation returns the offers compared to the budget.
+func handleEvaluation(router *mux.Router, db *Database, config Config) {
+	router.Path(pathPrefixAPI + "/evaluation").Methods("GET").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !can(r, permRead) {
+				handleError(w, errForbidden)
+				return
+			}
+
+			db, err := readDB(r, db)
+			if err != nil {
+				handleError(w, err)
+				return
+			}
+
+			if err := json.NewEncoder(w).Encode(db.Evaluation(config.Season)); err != nil {
+				handleError(w, fmt.Errorf("encoding evaluation: %w", err))
+				return
+			}
+		})
+}
+
+// handleGuide returns the guide value for an offer. It is public.
+func handleGuide(router *mux.Router, db *Database, config Config) {
+	router.Path(pathPrefixAPI + "/guide").Methods("GET").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := json.NewEncoder(w).Encode(db.Guide(config)); err != nil {
+				handleError(w, fmt.Errorf("encoding guide: %w", err))
+				return
+			}
+		})
+}
+
+// handleSEPAExport returns a pain.008 file with the direct debits for a
+// collection date.
+//
+// The date is given with the query parameter date. The parameter version can
+// be 02 (default) or 08.
+func handleSEPAExport(router *mux.Router, db *Database, config Config) {
+	router.Path(pathPrefixAPI + "/sepa.xml").Methods("GET").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !can(r, permExport) {
+				handleError(w, errForbidden)
+				return
+			}
+
+			date, err := time.Parse("2006-01-02", r.URL.Query().Get("date"))
+			if err != nil {
+				handleError(w, clientError{msg: "Ungültiges Datum. Erwartet wird YYYY-MM-DD"})
+				return
+			}
+
+			version := r.URL.Query().Get("version")
+			if version == "" {
+				version = "02"
+			}
+
+			bs, err := db.SEPAExport(config, version, date)
+			if err != nil {
+				handleError(w, fmt.Errorf("creating sepa export: %w", err))
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/xml")
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="lastschrift-%s.xml"`, date.Format("2006-01-02")))
+			w.Write(bs)
+		})
+}
+
+// handleExport returns the bieters as csv or xlsx file.
+//
+// The query parameter columns is a comma separated list of the exported
+// columns. The parameter sort is the column to sort by. The defaults are
+// taken from the config.
+func handleExport(router *mux.Router, db *Database, config Config) {
+	router.Path(pathPrefixAPI + "/export.{format:csv|xlsx}").Methods("GET").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !can(r, permExport) {
+			handleError(w, errForbidden)
+			return
+		}
+
+		columns := config.Export.Columns
+		if c := r.URL.Query().Get("columns"); c != "" {
+			columns = strings.Split(c, ",")
+		}
+
+		sortBy := config.Export.Sort
+		if s := r.URL.Query().Get("sort"); s != "" {
+			sortBy = s
+		}
+
+		db, err := readDB(r, db)
+		if err != nil {
+			handleError(w, err)
+			return
+		}
+
+		table, err := db.Export(config, columns, sortBy)
+		if err != nil {
+			handleError(w, fmt.Errorf("exporting bieter: %w", err))
+			return
+		}
+
+		format := mux.Vars(r)["format"]
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="bieter.%s"`, format))
+
+		if format == "csv" {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			err = table.writeCSV(w)
+		} else {
+			w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+			err = table.writeXLSX(w)
+		}
+
+		if err != nil {
+			log.Printf("Error: writing export: %v", err)
+		}
+	})
+}
+
+// handleImport creates bieters from a csv file in the body.
+//
+// With the query parameter dry_run=1, the file is only validated.
+func handleImport(router *mux.Router, db *Database, config Config) {
+	router.Path(pathPrefixAPI + "/import").Methods("POST").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dryRun := r.URL.Query().Get("dry_run") == "1"
+
+		result, err := db.ImportCSV(r.Body, dryRun, requestUser(r))
+		if err != nil {
+			handleError(w, fmt.Errorf("importing bieter: %w", err))
 			return
 		}
+
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			handleError(w, fmt.Errorf("encoding import result: %w", err))
+		}
 	})
 }
 
-func handleSetOffer(router *mux.Router, db *Database, config Config) {
+// ViewVerteilstelle is a verteilstelle returned to the client.
+type ViewVerteilstelle struct {
+	Verteilstelle
+	Full bool `json:"full"`
+}
+
+// handleVerteilstelle handles the verteilstellen.
+//
+// Everyone can get the list. Only admins can change it and get the numbers
+// of members and offers with /api/verteilstelle/count.
+func handleVerteilstelle(router *mux.Router, db *Database, config Config) {
+	path := pathPrefixAPI + "/verteilstelle"
+
+	router.Path(path).Methods("GET").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		counts := db.Verteilstellen()
+		list := make([]ViewVerteilstelle, len(counts))
+		for i, c := range counts {
+			list[i] = ViewVerteilstelle{Verteilstelle: c.Verteilstelle, Full: c.Full()}
+		}
+
+		if err := json.NewEncoder(w).Encode(list); err != nil {
+			handleError(w, fmt.Errorf("encoding verteilstellen: %w", err))
+		}
+	})
+
+	router.Path(path + "/count").Methods("GET").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := requestUser(r)
+		if !user.can(permRead) {
+			handleError(w, errForbidden)
+			return
+		}
+
+		counts := []VerteilstelleCount{}
+		for _, c := range db.Verteilstellen() {
+			if user.seesVerteilstelle(c.ID) {
+				counts = append(counts, c)
+			}
+		}
+
+		if err := json.NewEncoder(w).Encode(counts); err != nil {
+			handleError(w, fmt.Errorf("encoding verteilstellen: %w", err))
+		}
+	})
+
+	router.Path(path).Methods("POST").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v, err := db.AddVerteilstelle(r.Body, requestUser(r))
+		if err != nil {
+			handleError(w, fmt.Errorf("adding verteilstelle: %w", err))
+			return
+		}
+
+		if err := json.NewEncoder(w).Encode(v); err != nil {
+			handleError(w, fmt.Errorf("encoding verteilstelle: %w", err))
+		}
+	})
+
+	router.Path(path+"/{id:[0-9]+}").Methods("PUT", "DELETE").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+		if r.Method == "DELETE" {
+			if err := db.DeleteVerteilstelle(id, requestUser(r)); err != nil {
+				handleError(w, fmt.Errorf("deleting verteilstelle: %w", err))
+			}
+			return
+		}
+
+		v, err := db.UpdateVerteilstelle(id, r.Body, requestUser(r))
+		if err != nil {
+			handleError(w, fmt.Errorf("updating verteilstelle: %w", err))
+			return
+		}
+
+		if err := json.NewEncoder(w).Encode(v); err != nil {
+			handleError(w, fmt.Errorf("encoding verteilstelle: %w", err))
+		}
+	})
+}
+
+// handleArchive serves the bieters of old seasons.
+//
+// Like the current season, a single bieter can be read with its access token. The list of all bieters is only available to the admin.
+func handleArchive(router *mux.Router, archive map[string]*Database, config Config, limiter *failureLimiter) {
+	path := pathPrefixAPI + "/archive"
+
+	router.Path(path).Methods("GET").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seasons := make([]string, 0, len(archive))
+		for season := range archive {
+			seasons = append(seasons, season)
+		}
+		sort.Strings(seasons)
+
+		if err := json.NewEncoder(w).Encode(seasons); err != nil {
+			handleError(w, fmt.Errorf("encoding seasons: %w", err))
+		}
+	})
+
+	router.Path(path + "/{season}/bieter").Methods("GET").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := requestUser(r)
+		if !user.can(permRead) {
+			handleError(w, clientError{msg: "Passwort ist falsch", status: 401})
+			return
+		}
+
+		db, ok := archive[mux.Vars(r)["season"]]
+		if !ok {
+			handleError(w, clientError{msg: "Saison existiert nicht", status: 404})
+			return
+		}
+
+		var bieter []ViewBieter
+		for id, payload := range db.BieterList() {
+			if !user.seesBieter(payload) {
+				continue
+			}
+
+			bieter = append(bieter, ViewBieter{
+				ID:      id,
+				Payload: payload,
+				Offer:   db.Offer(id),
+				Rounds:  db.OfferHistory(id),
+			})
+		}
+
+		if err := json.NewEncoder(w).Encode(bieter); err != nil {
+			handleError(w, fmt.Errorf("encoding bieter: %w", err))
+		}
+	})
+
+	router.Path(path + "/{season}/bieter/{id}").Methods("GET").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		db, ok := archive[mux.Vars(r)["season"]]
+		if !ok {
+			handleError(w, clientError{msg: "Saison existiert nicht", status: 404})
+			return
+		}
+
+		bieterID, ok := findBieter(w, r, db, config, limiter, permRead)
+		if !ok {
+			return
+		}
+
+		payload, _ := db.Bieter(bieterID)
+		bieter := ViewBieter{
+			ID:      bieterID,
+			Payload: payload,
+			Offer:   db.Offer(bieterID),
+			Rounds:  db.OfferHistory(bieterID),
+		}
+
+		if err := json.NewEncoder(w).Encode(bieter); err != nil {
+			handleError(w, fmt.Errorf("encoding bieter: %w", err))
+		}
+	})
+}
+
+// handleHistory serves the audit log.
+//
+// GET /api/events returns all events and is only for users, that can read
+// everything. GET /api/bieter/{id}/history returns the events of one bieter.
+// Both can be filtered with the query parameters type, from and to. The
+// events can also be filtered with bieter.
+func handleHistory(router *mux.Router, db *Database, config Config, limiter *failureLimiter) {
+	router.Path(pathPrefixAPI + "/events").Methods("GET").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !can(r, permExport) {
+			handleError(w, errForbidden)
+			return
+		}
+
+		filter, err := historyFilterFromQuery(r.URL.Query())
+		if err != nil {
+			handleError(w, err)
+			return
+		}
+
+		entries, err := db.History(filter)
+		if err != nil {
+			handleError(w, fmt.Errorf("reading history: %w", err))
+			return
+		}
+
+		if err := json.NewEncoder(w).Encode(entries); err != nil {
+			handleError(w, fmt.Errorf("encoding history: %w", err))
+		}
+	})
+
+	router.Path(pathPrefixAPI + "/bieter/{id}/history").Methods("GET").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bieterID, ok := findBieter(w, r, db, config, limiter, permRead)
+		if !ok {
+			return
+		}
+
+		filter, err := historyFilterFromQuery(r.URL.Query())
+		if err != nil {
+			handleError(w, err)
+			return
+		}
+		filter.Bieter = bieterID
+
+		entries, err := db.History(filter)
+		if err != nil {
+			handleError(w, fmt.Errorf("reading history: %w", err))
+			return
+		}
+
+		// A bieter does not see, from where the admins did their changes.
+		if !can(r, permRead) {
+			for i := range entries {
+				entries[i].IP = ""
+				entries[i].RequestID = ""
+			}
+		}
+
+		if err := json.NewEncoder(w).Encode(entries); err != nil {
+			handleError(w, fmt.Errorf("encoding history: %w", err))
+		}
+	})
+}
+
+// readDB returns the database for a request. With the query parameter at, it
+// is the database at this sequence number or time. Only users with permRead
+// can use the parameter and only for GET requests.
+func readDB(r *http.Request, db *Database) (*Database, error) {
+	at := r.URL.Query().Get("at")
+	if at == "" {
+		return db, nil
+	}
+
+	if !can(r, permRead) {
+		return nil, errForbidden
+	}
+
+	if r.Method != "GET" {
+		return nil, clientError{msg: "Ein früherer Stand kann nicht geändert werden"}
+	}
+
+	limit, err := parseReplayLimit(at)
+	if err != nil {
+		return nil, err
+	}
+
+	past, err := db.At(limit)
+	if err != nil {
+		return nil, fmt.Errorf("loading database at %q: %w", at, err)
+	}
+	return past, nil
+}
+
+// viewToken returns the access token of a bieter, if the user of the request
+// can see it. Only users with permWrite see the tokens of all bieters. key is
+// the value from the url, so a bieter sees its own token.
+func viewToken(r *http.Request, db *Database, id, key string) string {
+	token := db.Token(id)
+	if can(r, permWrite) || (token != "" && key == token) {
+		return token
+	}
+	return ""
+}
+
+// findBieter returns the id of the bieter from the url. Users with the
+// permission p can use the id instead of the access token. Failed lookups are
+// counted by the limiter.
+func findBieter(w http.ResponseWriter, r *http.Request, db *Database, config Config, limiter *failureLimiter, p Permission) (string, bool) {
+	bieterID, exist := db.FindBieter(mux.Vars(r)["id"], requestUser(r), p)
+	if !exist {
+		limiter.fail(clientIP(r, config.RateLimit), "bieter lookup")
+		handleError(w, clientError{msg: "Bieter existiert nicht", status: 404})
+		return "", false
+	}
+	return bieterID, true
+}
+
+// handleToken creates new access tokens.
+//
+// POST /api/bieter/{id}/token gives one bieter a new token. POST /api/tokens
+// gives all bieters without a token a new one.
+func handleToken(router *mux.Router, db *Database, config Config) {
+	router.Path(pathPrefixAPI + "/bieter/{id}/token").Methods("POST").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := requestUser(r)
+		bieterID, exist := db.FindBieter(mux.Vars(r)["id"], user, permWrite)
+		if !exist {
+			handleError(w, clientError{msg: "Bieter existiert nicht", status: 404})
+			return
+		}
+
+		token, err := db.RotateToken(bieterID, user)
+		if err != nil {
+			handleError(w, fmt.Errorf("rotating token: %w", err))
+			return
+		}
+
+		if err := json.NewEncoder(w).Encode(map[string]string{"id": bieterID, "token": token}); err != nil {
+			handleError(w, fmt.Errorf("encoding token: %w", err))
+		}
+	})
+
+	router.Path(pathPrefixAPI + "/tokens").Methods("POST").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count, err := db.IssueTokens(requestUser(r))
+		if err != nil {
+			handleError(w, fmt.Errorf("issuing tokens: %w", err))
+			return
+		}
+
+		if err := json.NewEncoder(w).Encode(map[string]int{"created": count}); err != nil {
+			handleError(w, fmt.Errorf("encoding token count: %w", err))
+		}
+	})
+}
+
+func handleSetOffer(router *mux.Router, db *Database, config Config, limiter *failureLimiter) {
 	router.Path(pathPrefixAPI + "/offer/{id}").Methods("PUT").
 		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			bieterID := mux.Vars(r)["id"]
+			bieterID, ok := findBieter(w, r, db, config, limiter, permWrite)
+			if !ok {
+				return
+			}
 
-			if err := db.UpdateOffer(bieterID, r.Body, isAdmin(r, config)); err != nil {
+			if err := db.UpdateOffer(bieterID, r.Body, config.MinOffer, requestUser(r)); err != nil {
 				handleError(w, fmt.Errorf("save offer: %w", err))
 				return
 			}
@@ -347,6 +1135,46 @@ func loggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// requestInfo is the data of a request, that is saved with the events.
+type requestInfo struct {
+	ip string
+	id string
+}
+
+// requestMiddleware adds the ip and a request id to the request context. The
+// request id is taken from the header X-Request-ID or created and returned in
+// the same header.
+func requestMiddleware(config Config) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get("X-Request-ID")
+			if !validRequestID(id) {
+				b := make([]byte, 12)
+				rand.Read(b)
+				id = base64.RawURLEncoding.EncodeToString(b)
+			}
+			w.Header().Set("X-Request-ID", id)
+
+			info := requestInfo{ip: clientIP(r, config.RateLimit), id: id}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestKey, info)))
+		})
+	}
+}
+
+// validRequestID returns true, if a request id from the client can be saved.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
 func handleError(w http.ResponseWriter, err error) {
 	msg := "Interner Fehler"
 	status := 500
@@ -395,12 +1223,3 @@ func (err clientError) httpStatus() int {
 	}
 	return err.status
 }
-
-func isAdmin(r *http.Request, c Config) bool {
-	if c.AdminPW == "" {
-		return false
-	}
-
-	adminPW := r.Header.Get("Auth")
-	return adminPW == c.AdminPW
-}
@@ -0,0 +1,286 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HistoryEntry is one event of the audit log.
+type HistoryEntry struct {
+	Seq       int        `json:"seq"`
+	Type      string     `json:"type"`
+	Time      recordTime `json:"time"`
+	User      string     `json:"user,omitempty"`
+	Role      Role       `json:"role,omitempty"`
+	IP        string     `json:"ip,omitempty"`
+	RequestID string     `json:"request_id,omitempty"`
+
+	// Bieter is the id of the bieter of an update, delete, token or offer
+	// event.
+	Bieter string `json:"bieter,omitempty"`
+
+	// Changes are the fields of the bieter, that were changed by the event.
+	Changes []FieldChange `json:"changes,omitempty"`
+
+	// Payload is the payload of events, that do not belong to a bieter.
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// FieldChange is the change of one field of a bieter. Old is empty for a new
+// field and New is empty for a removed field.
+type FieldChange struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old,omitempty"`
+	New   json.RawMessage `json:"new,omitempty"`
+}
+
+// HistoryFilter selects the entries of the audit log. Empty fields match all
+// entries.
+type HistoryFilter struct {
+	Types  []string
+	Bieter string
+
+	// From is inclusive, To is exclusive.
+	From time.Time
+	To   time.Time
+}
+
+func (f HistoryFilter) match(e HistoryEntry) bool {
+	if len(f.Types) > 0 && !slices.Contains(f.Types, e.Type) {
+		return false
+	}
+
+	if f.Bieter != "" && e.Bieter != f.Bieter {
+		return false
+	}
+
+	if !f.From.IsZero() && e.Time.Before(f.From) {
+		return false
+	}
+
+	if !f.To.IsZero() && !e.Time.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+// historyFilterFromQuery reads the filter from the query parameters type,
+// bieter, from and to.
+//
+// type is a comma separated list of event types. from and to are a date or a
+// time in the format of the records. A date in to includes the whole day.
+func historyFilterFromQuery(q url.Values) (HistoryFilter, error) {
+	var filter HistoryFilter
+	if t := q.Get("type"); t != "" {
+		for _, name := range strings.Split(t, ",") {
+			if getEvent(name) == nil {
+				return HistoryFilter{}, clientError{msg: fmt.Sprintf("Unbekannter Typ %q", name)}
+			}
+			filter.Types = append(filter.Types, name)
+		}
+	}
+
+	filter.Bieter = q.Get("bieter")
+
+	var err error
+	if filter.From, err = parseHistoryTime(q.Get("from"), false); err != nil {
+		return HistoryFilter{}, err
+	}
+
+	if filter.To, err = parseHistoryTime(q.Get("to"), true); err != nil {
+		return HistoryFilter{}, err
+	}
+	return filter, nil
+}
+
+// parseHistoryTime parses a date or time in local time. If endOfDay is true, a
+// date is the beginning of the next day.
+func parseHistoryTime(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.ParseInLocation(recordTimeFormat, value, time.Local); err == nil {
+		return t, nil
+	}
+
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, clientError{msg: fmt.Sprintf("Ungültige Zeit %q. Erwartet wird YYYY-MM-DD oder YYYY-MM-DD HH:MM:SS", value)}
+	}
+
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// History returns the entries of the audit log, that match the filter.
+//
+// The events of the store are replayed into an empty database, to compare
+// the bieter data before and after each event. After a compaction, the
+// history starts with the compacted events.
+func (db *Database) History(filter HistoryFilter) ([]HistoryEntry, error) {
+	replayed := emptyDatabase()
+	var entries []HistoryEntry
+
+	err := db.store.Iterate(func(bs []byte) error {
+		replayed.events++
+		record, err := decodeRecord(bs, replayed.events)
+		if err != nil {
+			return err
+		}
+
+		event, err := record.event()
+		if err != nil {
+			return err
+		}
+
+		bieterID := eventBieter(event)
+		oldPayload := replayed.bieter[bieterID]
+		oldOffer, hadOffer := replayed.offer[bieterID]
+
+		if err := event.execute(replayed); err != nil {
+			return fmt.Errorf("executing event %q: %w", record.Type, err)
+		}
+
+		entry := HistoryEntry{
+			Seq:       record.Seq,
+			Type:      record.Type,
+			Time:      record.Time,
+			User:      record.User,
+			Role:      record.Role,
+			IP:        record.IP,
+			RequestID: record.RequestID,
+			Bieter:    bieterID,
+		}
+
+		switch event.(type) {
+		case *eventUpdate, *eventDelete:
+			entry.Changes, err = diffPayload(oldPayload, replayed.bieter[bieterID])
+			if err != nil {
+				return fmt.Errorf("event %d: %w", record.Seq, err)
+			}
+
+		case *eventOffer:
+			change := FieldChange{Field: "offer"}
+			if hadOffer {
+				change.Old = json.RawMessage(fmt.Sprint(oldOffer))
+			}
+			change.New = json.RawMessage(fmt.Sprint(replayed.offer[bieterID]))
+			entry.Changes = []FieldChange{change}
+
+		case *eventImport:
+			// Each imported bieter is shown as its own entry.
+			for _, b := range event.(*eventImport).Bieter {
+				bieterEntry := entry
+				bieterEntry.Bieter = b.ID
+				bieterEntry.Changes, err = diffPayload(nil, b.Payload)
+				if err != nil {
+					return fmt.Errorf("event %d: %w", record.Seq, err)
+				}
+
+				if filter.match(bieterEntry) {
+					entries = append(entries, bieterEntry)
+				}
+			}
+			return nil
+
+		case *eventToken:
+			// The token is a secret and is not shown.
+
+		default:
+			entry.Payload = record.Payload
+		}
+
+		if filter.match(entry) {
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading events: %w", err)
+	}
+
+	return entries, nil
+}
+
+// eventBieter returns the id of the bieter of an event or an empty string,
+// if the event does not belong to one bieter.
+func eventBieter(event Event) string {
+	switch e := event.(type) {
+	case *eventUpdate:
+		return e.ID
+	case *eventDelete:
+		return e.ID
+	case *eventToken:
+		return e.ID
+	case *eventOffer:
+		return e.ID
+	default:
+		return ""
+	}
+}
+
+// diffPayload returns the changed top level fields of two bieter payloads. An
+// empty payload is a bieter without fields.
+func diffPayload(old, updated json.RawMessage) ([]FieldChange, error) {
+	oldFields, err := payloadFields(old)
+	if err != nil {
+		return nil, err
+	}
+
+	newFields, err := payloadFields(updated)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(oldFields)+len(newFields))
+	for name := range oldFields {
+		names = append(names, name)
+	}
+	for name := range newFields {
+		if _, ok := oldFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []FieldChange
+	for _, name := range names {
+		o, n := oldFields[name], newFields[name]
+		if bytes.Equal(o, n) {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: name, Old: o, New: n})
+	}
+	return changes, nil
+}
+
+// payloadFields returns the top level fields of a payload. The values are
+// compacted, so they can be compared.
+func payloadFields(payload json.RawMessage) (map[string]json.RawMessage, error) {
+	if len(payload) == 0 {
+		return nil, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, fmt.Errorf("decoding bieter payload: %w", err)
+	}
+
+	for name, value := range fields {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, value); err != nil {
+			return nil, fmt.Errorf("compacting field %q: %w", name, err)
+		}
+		fields[name] = buf.Bytes()
+	}
+	return fields, nil
+}
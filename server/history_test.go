@@ -0,0 +1,68 @@
+package server
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	db, err := NewDB(NewMemoryStore(
+		`{"type":"update","time":"2022-03-01 18:30:00","payload":{"id":"1","payload":{"name":"Hugo","mail":"a@example.com"}}}`,
+		`{"type":"state","time":"2022-03-02 10:00:00","payload":{"state":3}}`,
+		`{"type":"offer","time":"2022-03-03 12:00:00","payload":{"id":"1","offer":5000}}`,
+		`{"type":"update","time":"2022-03-04 09:00:00","payload":{"id":"2","payload":{"name":"Erika"}}}`,
+	))
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+
+	if err := db.UpdateOffer("1", strings.NewReader(`{"offer":6000}`), 0, systemUser); err != nil {
+		t.Fatalf("UpdateOffer: %v", err)
+	}
+	if _, err := db.UpdateBieter("1", strings.NewReader(`{"name": "Hugo","mail":"b@example.com"}`), systemUser); err != nil {
+		t.Fatalf("UpdateBieter: %v", err)
+	}
+
+	entries, err := db.History(HistoryFilter{Bieter: "1"})
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+
+	if len(entries) != 4 {
+		t.Fatalf("got %d entries, expected 4: %v", len(entries), entries)
+	}
+
+	if got := entries[0].Changes; len(got) != 2 || got[0].Old != nil {
+		t.Errorf("create has changes %v, expected two new fields", got)
+	}
+
+	offer := entries[2].Changes
+	if len(offer) != 1 || string(offer[0].Old) != "5000" || string(offer[0].New) != "6000" {
+		t.Errorf("offer has changes %v, expected 5000 -> 6000", offer)
+	}
+
+	update := entries[3]
+	if len(update.Changes) != 1 || update.Changes[0].Field != "mail" || string(update.Changes[0].New) != `"b@example.com"` {
+		t.Errorf("update has changes %v, expected only the new mail", update.Changes)
+	}
+	if update.User != "system" || update.Seq != 6 {
+		t.Errorf("update has user %q and seq %d, expected system and 6", update.User, update.Seq)
+	}
+
+	filter, err := historyFilterFromQuery(map[string][]string{"type": {"state,offer"}, "from": {"2022-03-02"}, "to": {"2022-03-03"}})
+	if err != nil {
+		t.Fatalf("historyFilterFromQuery: %v", err)
+	}
+
+	entries, err = db.History(filter)
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Type != "state" || entries[1].Type != "offer" {
+		t.Errorf("got %v, expected the state and the first offer", entries)
+	}
+
+	if _, err := historyFilterFromQuery(map[string][]string{"type": {"unknown"}}); err == nil {
+		t.Errorf("unknown type was accepted")
+	}
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed" // Needed for the bank code table.
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ibanLengths is the length of an IBAN for each country of the SEPA area.
+var ibanLengths = map[string]int{
+	"AD": 24, "AT": 20, "BE": 16, "BG": 22, "CH": 21, "CY": 28, "CZ": 24,
+	"DE": 22, "DK": 18, "EE": 20, "ES": 24, "FI": 18, "FO": 18, "FR": 27,
+	"GB": 22, "GI": 23, "GL": 18, "GR": 27, "HR": 21, "HU": 28, "IE": 22,
+	"IS": 26, "IT": 27, "LI": 21, "LT": 20, "LU": 20, "LV": 21, "MC": 27,
+	"MT": 31, "NL": 18, "NO": 15, "PL": 28, "PT": 25, "RO": 24, "SE": 24,
+	"SI": 19, "SK": 24, "SM": 27, "VA": 22,
+}
+
+// normalizeIBAN removes all spaces and converts the IBAN to upper case.
+func normalizeIBAN(iban string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(iban), ""))
+}
+
+// validateIBAN checks the country, the length and the checksum of an IBAN.
+//
+// The returned error is a message for the client.
+func validateIBAN(iban string) error {
+	iban = normalizeIBAN(iban)
+
+	if len(iban) < 4 {
+		return validationError{"Die IBAN ist zu kurz"}
+	}
+
+	for _, r := range iban {
+		if !(r >= '0' && r <= '9' || r >= 'A' && r <= 'Z') {
+			return validationError{"Die IBAN darf nur Buchstaben und Ziffern enthalten"}
+		}
+	}
+
+	length, ok := ibanLengths[iban[:2]]
+	if !ok {
+		return validationError{fmt.Sprintf("Das Land %q ist nicht im SEPA-Raum", iban[:2])}
+	}
+
+	if len(iban) != length {
+		return validationError{fmt.Sprintf("Eine IBAN aus %s muss %d Zeichen lang sein, nicht %d", iban[:2], length, len(iban))}
+	}
+
+	if ibanMod97(iban) != 1 {
+		return validationError{"Die Prüfsumme der IBAN ist falsch"}
+	}
+	return nil
+}
+
+// ibanMod97 calculates the checksum of an IBAN after ISO 13616. The IBAN has to
+// be normalized.
+func ibanMod97(iban string) int {
+	rearranged := iban[4:] + iban[:4]
+
+	var mod int
+	for _, r := range rearranged {
+		if r >= 'A' && r <= 'Z' {
+			mod = (mod*100 + int(r-'A') + 10) % 97
+			continue
+		}
+		mod = (mod*10 + int(r-'0')) % 97
+	}
+	return mod
+}
+
+//go:embed blz.csv
+var blzFile []byte
+
+var (
+	blzOnce  sync.Once
+	blzToBIC map[string]string
+)
+
+// bicForIBAN returns the BIC for a german IBAN from the bundled bank code
+// table. Returns false, if the IBAN is not german or the bank is unknown.
+func bicForIBAN(iban string) (string, bool) {
+	iban = normalizeIBAN(iban)
+	if len(iban) != ibanLengths["DE"] || !strings.HasPrefix(iban, "DE") {
+		return "", false
+	}
+
+	blzOnce.Do(func() {
+		blzToBIC = parseBLZTable(blzFile)
+	})
+
+	bic, ok := blzToBIC[iban[4:12]]
+	return bic, ok
+}
+
+// parseBLZTable parses the bank code table. Each line has the format
+// blz;bic;name. Lines starting with # are ignored.
+func parseBLZTable(content []byte) map[string]string {
+	table := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.Split(line, ";")
+		if len(parts) < 2 {
+			continue
+		}
+		table[parts[0]] = parts[1]
+	}
+	return table
+}
@@ -0,0 +1,66 @@
+package server
+
+import "testing"
+
+func TestValidateIBAN(t *testing.T) {
+	for _, tt := range []struct {
+		iban  string
+		valid bool
+	}{
+		{"DE89370400440532013000", true},
+		{"DE89 3704 0044 0532 0130 00", true},
+		{"de89370400440532013000", true},
+		{"GB82WEST12345698765432", true},
+		{"DE88370400440532013000", false},
+		{"DE8937040044053201300", false},
+		{"XX89370400440532013000", false},
+		{"DE89-3704-0044-0532-0130-00", false},
+		{"DE", false},
+	} {
+		err := validateIBAN(tt.iban)
+		if tt.valid && err != nil {
+			t.Errorf("validateIBAN(%q) returned %v, expected valid", tt.iban, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("validateIBAN(%q) returned no error", tt.iban)
+		}
+	}
+}
+
+func TestBICForIBAN(t *testing.T) {
+	bic, ok := bicForIBAN("DE89 3704 0044 0532 0130 00")
+	if !ok || bic != "COBADEFFXXX" {
+		t.Errorf("got %q, %v, expected COBADEFFXXX", bic, ok)
+	}
+
+	if _, ok := bicForIBAN("DE02120300000000202051"); !ok {
+		t.Errorf("BIC for DKB not found")
+	}
+
+	if _, ok := bicForIBAN("GB82WEST12345698765432"); ok {
+		t.Errorf("found BIC for a british IBAN")
+	}
+}
+
+// TestBICTableComplete checks banks, that are not in the excerpt of the bank
+// code table. It fails, until server/blz.csv is generated from the complete
+// file of the Bundesbank with tools/blz.
+func TestBICTableComplete(t *testing.T) {
+	for blz, expect := range map[string]string{
+		"20040000": "COBADEHHXXX", // Commerzbank Hamburg
+		"25050180": "SPKHDE2HXXX", // Sparkasse Hannover
+		"36050105": "SPESDE3EXXX", // Sparkasse Essen
+		"44050199": "DORTDE33XXX", // Sparkasse Dortmund
+		"50050201": "HELADEF1822", // Frankfurter Sparkasse
+		"86055592": "WELADE8LXXX", // Sparkasse Leipzig
+	} {
+		bic, ok := bicForIBAN("DE00" + blz + "0000000000")
+		if !ok {
+			t.Errorf("BLZ %s is not in server/blz.csv. Generate the file with tools/blz", blz)
+			continue
+		}
+		if bic != expect {
+			t.Errorf("BLZ %s has BIC %s, expected %s", blz, bic, expect)
+		}
+	}
+}
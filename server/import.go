@@ -0,0 +1,323 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// importIgnored are export columns, that are not imported.
+var importIgnored = map[string]bool{"id": true, "offer": true, "yearly": true}
+
+// ImportResult is the result of a csv import.
+type ImportResult struct {
+	DryRun bool `json:"dry_run"`
+
+	// Created are the bieters, that were created. On a dry run, the bieters
+	// that would be created.
+	Created []ImportRow `json:"created"`
+
+	// Skipped are the rows with an email address, that already exists.
+	Skipped []ImportRow `json:"skipped"`
+
+	// Errors are the invalid rows. If there is an error, no bieter is
+	// created.
+	Errors []ImportRow `json:"errors"`
+}
+
+// ImportRow is one row of the csv file.
+type ImportRow struct {
+	// Row is the line in the csv file. The header is row 1.
+	Row  int    `json:"row"`
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name"`
+	Mail string `json:"mail"`
+	Msg  string `json:"msg,omitempty"`
+}
+
+// ImportCSV creates bieters from a csv file.
+//
+// The first row are the column names. They can be the keys or the titles of
+// the export. Semicolons and commas are allowed as separator. Rows with an
+// email address, that already exists, are skipped.
+//
+// All rows are validated first. Bieters are only created, if there is no
+// invalid row and dryRun is false. They are created with one event, so either
+// all or none of them are saved.
+func (db *Database) ImportCSV(r io.Reader, dryRun bool, user User) (ImportResult, error) {
+	if !user.can(permWrite) {
+		return ImportResult{}, errForbidden
+	}
+
+	rows, err := db.parseImport(r)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	result := ImportResult{DryRun: dryRun}
+	var payloads []json.RawMessage
+	for _, row := range rows {
+		switch {
+		case row.err != nil:
+			result.Errors = append(result.Errors, row.result(row.err.Error()))
+
+		case row.duplicate:
+			result.Skipped = append(result.Skipped, row.result("E-Mail-Adresse existiert bereits"))
+
+		default:
+			result.Created = append(result.Created, row.result(""))
+			payloads = append(payloads, row.payload)
+		}
+	}
+
+	if dryRun || len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	if len(payloads) == 0 {
+		return result, nil
+	}
+
+	ids, err := db.importBieter(payloads, user)
+	if err != nil {
+		result.Created = nil
+		return result, fmt.Errorf("creating bieters: %w", err)
+	}
+
+	for i, id := range ids {
+		result.Created[i].ID = id
+	}
+	return result, nil
+}
+
+type importRow struct {
+	row       int
+	data      bieterData
+	payload   json.RawMessage
+	duplicate bool
+	err       error
+}
+
+func (r importRow) result(msg string) ImportRow {
+	return ImportRow{Row: r.row, Name: r.data.Name, Mail: r.data.Mail, Msg: msg}
+}
+
+// Import imports a csv file into the configured database and prints the
+// result.
+//
+// It fails, if the database file is used by the running server.
+func Import(configFile, dbFile, csvFile string, dryRun bool) error {
+	f, err := os.Open(csvFile)
+	if err != nil {
+		return fmt.Errorf("open csv file: %w", err)
+	}
+	defer f.Close()
+
+	_, db, err := openConfigDB(configFile, dbFile)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	result, err := db.ImportCSV(f, dryRun, systemUser)
+	printImportResult(result)
+	if err != nil {
+		return fmt.Errorf("importing: %w", err)
+	}
+
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("%d invalid rows. Nothing was imported", len(result.Errors))
+	}
+	return nil
+}
+
+func printImportResult(result ImportResult) {
+	for _, row := range result.Errors {
+		fmt.Printf("Zeile %d (%s): %s\n", row.Row, row.Name, row.Msg)
+	}
+
+	for _, row := range result.Skipped {
+		fmt.Printf("Zeile %d (%s): Übersprungen, %s\n", row.Row, row.Name, row.Msg)
+	}
+
+	if result.DryRun {
+		fmt.Printf("Dry run: %d Bieter würden angelegt\n", len(result.Created))
+		return
+	}
+	fmt.Printf("%d Bieter angelegt\n", len(result.Created))
+}
+
+// parseImport reads and validates the rows of the csv file.
+func (db *Database) parseImport(r io.Reader) ([]importRow, error) {
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(3); err == nil && bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		br.Discard(3)
+	}
+
+	// Use the separator, that is used more often in the first line.
+	firstLine, _ := br.Peek(br.Buffered())
+	if i := bytes.IndexByte(firstLine, '\n'); i >= 0 {
+		firstLine = firstLine[:i]
+	}
+
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = -1
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		cr.Comma = ';'
+	}
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, validationError{fmt.Sprintf("Die CSV-Datei kann nicht gelesen werden: %v", err)}
+	}
+
+	columns := make([]string, len(header))
+	for i, title := range header {
+		key, ok := importColumn(title)
+		if !ok {
+			return nil, validationError{fmt.Sprintf("Unbekannte Spalte %q", title)}
+		}
+		columns[i] = key
+	}
+
+	db.RLock()
+	defer db.RUnlock()
+
+	mails := make(map[string]bool)
+	for _, payload := range db.bieter {
+		var data struct {
+			Mail string `json:"mail"`
+		}
+		json.Unmarshal(payload, &data)
+		if data.Mail != "" {
+			mails[strings.ToLower(data.Mail)] = true
+		}
+	}
+
+	var rows []importRow
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, validationError{fmt.Sprintf("Zeile %d kann nicht gelesen werden: %v", line, err)}
+		}
+
+		row := db.parseImportRow(line, columns, record)
+		if row.err == nil && row.data.Mail != "" {
+			mail := strings.ToLower(row.data.Mail)
+			row.duplicate = mails[mail]
+			mails[mail] = true
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// csvUnescape removes the prefix, that csvText adds to cells that start like a
+// formula, so an exported file can be imported again.
+func csvUnescape(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune(csvFormulaPrefixes, rune(s[1])) {
+		return s[1:]
+	}
+	return s
+}
+
+// parseImportRow converts one record to a bieter.
+//
+// Has to be called with the read lock.
+func (db *Database) parseImportRow(line int, columns []string, record []string) importRow {
+	row := importRow{row: line}
+	values := make(map[string]string, len(columns))
+	for i, key := range columns {
+		if i < len(record) {
+			values[key] = strings.TrimSpace(csvUnescape(record[i]))
+		}
+	}
+
+	row.data = bieterData{
+		Name:            values["name"],
+		Teilpartner:     values["teilpartner"],
+		Mail:            values["mail"],
+		TeilpartnerMail: values["teilpartnerMail"],
+		Kontoinhaber:    values["kontoinhaber"],
+		Mitglied:        values["mitglied"],
+		Adresse:         values["adresse"],
+		IBAN:            values["iban"],
+	}
+
+	var errs fieldErrors
+	if v := values["verteilstelle"]; v != "" {
+		id, ok := db.findVerteilstelle(v)
+		if !ok {
+			errs = append(errs, fieldError{"verteilstelle", fmt.Sprintf("Unbekannte Verteilstelle %q", v)})
+		}
+		row.data.Verteilstelle = verteilstelle(id)
+	}
+
+	switch strings.ToLower(values["abbuchung"]) {
+	case "", "0", "monatlich":
+		row.data.Abbuchung = abbuchungMonthly
+	case "1", "jährlich", "jaehrlich":
+		row.data.Abbuchung = abbuchungYearly
+	default:
+		errs = append(errs, fieldError{"abbuchung", fmt.Sprintf("Unbekannte Abbuchung %q", values["abbuchung"])})
+	}
+
+	if len(errs) > 0 {
+		row.err = errs
+		return row
+	}
+
+	payload, err := json.Marshal(row.data)
+	if err != nil {
+		row.err = fmt.Errorf("encoding payload: %w", err)
+		return row
+	}
+
+	if _, err := decodeBieterData(payload); err != nil {
+		row.err = err
+		return row
+	}
+
+	row.payload = payload
+	return row
+}
+
+// findVerteilstelle returns the id of a verteilstelle by its name or id.
+//
+// Has to be called with the read lock.
+func (db *Database) findVerteilstelle(nameOrID string) (int, bool) {
+	if id, err := strconv.Atoi(nameOrID); err == nil {
+		_, ok := db.verteilstellen[id]
+		return id, ok
+	}
+
+	for _, v := range db.verteilstellen {
+		if strings.EqualFold(v.Name, nameOrID) {
+			return v.ID, true
+		}
+	}
+	return 0, false
+}
+
+// importColumn returns the key of a column by its key or export title.
+func importColumn(title string) (string, bool) {
+	title = strings.TrimSpace(title)
+	for _, column := range exportColumns {
+		if strings.EqualFold(column.key, title) || strings.EqualFold(column.title, title) {
+			if importIgnored[column.key] {
+				return "", true
+			}
+			return column.key, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImportCSV(t *testing.T) {
+	store := NewMemoryStore(
+		`{"type":"verteilstelle","payload":{"id":1,"name":"Villingen"}}`,
+		`{"type":"update","payload":{"id":"1","payload":{"name":"Hugo","mail":"hugo@example.com"}}}`,
+	)
+	db, err := NewDB(store)
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+
+	csv := "\ufeffName;E-Mail;Verteilstelle;Abbuchung;Gebot monatlich\n" +
+		"Erika;erika@example.com;Villingen;Jährlich;50,00\n" +
+		"Hugo Müller;HUGO@example.com;1;;\n" +
+		"Anna;anna@example.com;;monatlich;\n" +
+		"Erika Zwei;erika@example.com;;;\n"
+
+	result, err := db.ImportCSV(strings.NewReader(csv), true, systemUser)
+	if err != nil {
+		t.Fatalf("ImportCSV: %v", err)
+	}
+
+	if len(result.Created) != 2 || len(result.Skipped) != 2 || len(result.Errors) != 0 {
+		t.Fatalf("got %d created, %d skipped and %d errors, expected 2, 2 and 0", len(result.Created), len(result.Skipped), len(result.Errors))
+	}
+
+	if got := len(db.BieterList()); got != 1 {
+		t.Fatalf("dry run created bieters. Got %d, expected 1", got)
+	}
+
+	result, err = db.ImportCSV(strings.NewReader(csv), false, systemUser)
+	if err != nil {
+		t.Fatalf("ImportCSV: %v", err)
+	}
+
+	if got := len(db.BieterList()); got != 3 {
+		t.Errorf("got %d bieters, expected 3", got)
+	}
+
+	payload, _ := db.Bieter(result.Created[0].ID)
+	data, err := decodeBieterData(payload)
+	if err != nil {
+		t.Fatalf("decoding payload: %v", err)
+	}
+	if data.Name != "Erika" || data.Verteilstelle != 1 || data.Abbuchung != abbuchungYearly {
+		t.Errorf("got bieter %+v", data)
+	}
+
+	var records int
+	store.Iterate(func([]byte) error {
+		records++
+		return nil
+	})
+	if records != 3 {
+		t.Errorf("store has %d records, expected the import as one record", records)
+	}
+
+	reloaded, err := NewDB(store)
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	if got := len(reloaded.BieterList()); got != 3 {
+		t.Errorf("reloaded database has %d bieters, expected 3", got)
+	}
+	if reloaded.bieterToken[result.Created[1].ID] == "" {
+		t.Errorf("imported bieter has no token after reload")
+	}
+	entries, err := db.History(HistoryFilter{Bieter: result.Created[1].ID})
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Type != "import" || len(entries[0].Changes) == 0 {
+		t.Errorf("got history %v, expected the import", entries)
+	}
+}
+
+func TestImportCSVInvalid(t *testing.T) {
+	db, err := NewDB(NewMemoryStore())
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+
+	csv := "name,mail,verteilstelle\n" +
+		"Erika,erika@example.com,\n" +
+		",no-name@example.com,\n" +
+		"Hugo,hugo@example.com,Nirgendwo\n"
+
+	result, err := db.ImportCSV(strings.NewReader(csv), false, systemUser)
+	if err != nil {
+		t.Fatalf("ImportCSV: %v", err)
+	}
+
+	if len(result.Errors) != 2 || result.Errors[0].Row != 3 || result.Errors[1].Row != 4 {
+		t.Errorf("got errors %v, expected errors in row 3 and 4", result.Errors)
+	}
+
+	if got := len(db.BieterList()); got != 0 {
+		t.Errorf("got %d bieters, expected none, since the file has errors", got)
+	}
+
+	if _, err := db.ImportCSV(strings.NewReader("name,unknown\n"), false, systemUser); err == nil {
+		t.Errorf("got no error for an unknown column")
+	}
+}
+
+func TestImportCSVFormula(t *testing.T) {
+	db, err := NewDB(NewMemoryStore())
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+
+	csv := "Name;Adresse;E-Mail\n" +
+		"'=1+1;'+49 7721;erika@example.com\n" +
+		"'\t@Hugo;\"'\r-1\";hugo@example.com\n"
+
+	result, err := db.ImportCSV(strings.NewReader(csv), false, systemUser)
+	if err != nil {
+		t.Fatalf("ImportCSV: %v", err)
+	}
+
+	if len(result.Created) != 2 {
+		t.Fatalf("got %d created bieters, expected 2: %+v", len(result.Created), result)
+	}
+
+	for i, expect := range []bieterData{
+		{Name: "=1+1", Adresse: "+49 7721"},
+		{Name: "@Hugo", Adresse: "-1"},
+	} {
+		payload, _ := db.Bieter(result.Created[i].ID)
+		data, err := decodeBieterData(payload)
+		if err != nil {
+			t.Fatalf("decoding payload: %v", err)
+		}
+		if data.Name != expect.Name || data.Adresse != expect.Adresse {
+			t.Errorf("bieter %d has name %q and adresse %q, expected %q and %q", i+1, data.Name, data.Adresse, expect.Name, expect.Adresse)
+		}
+	}
+}
@@ -4,16 +4,65 @@ import (
 	"bytes"
 	"fmt"
 	"log"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/johnfercher/maroto/pkg/consts"
 	"github.com/johnfercher/maroto/pkg/pdf"
 	"github.com/johnfercher/maroto/pkg/props"
 )
 
+// Sizes to estimate the height of a paragraph with font size 10.
+const (
+	pdfCharsPerLine = 95
+	pdfLineHeight   = 3.6
+)
+
 // Bietervertrag creates the bietervertrag pdf for a bieter
-func Bietervertrag(domain string, bieterID string, headerImage string, data pdfData) (*bytes.Buffer, error) {
+func Bietervertrag(c Config, ct *ContractTemplate, bieterID string, headerImage string, data pdfData) (*bytes.Buffer, error) {
 	m := pdf.NewMaroto(consts.Portrait, consts.A4)
 
+	if err := addBietervertrag(m, c, ct, bieterID, headerImage, data); err != nil {
+		return nil, err
+	}
+
+	pdfile, err := m.Output()
+	if err != nil {
+		return nil, fmt.Errorf("creating pdf: %w", err)
+	}
+
+	return &pdfile, nil
+}
+
+// addBietervertrag adds the pages of the bietervertrag of a bieter to m.
+func addBietervertrag(m pdf.Maroto, c Config, ct *ContractTemplate, bieterID string, headerImage string, data pdfData) error {
+	amount := data.offer
+	if data.Abbuchung == abbuchungYearly {
+		amount *= c.Season.payments()
+	}
+
+	cd := contractData{
+		bieterData:       data.bieterData,
+		ID:               bieterID,
+		Verteilstelle:    data.verteilstelle,
+		Abbuchung:        strings.ToLower(data.Abbuchung.String()),
+		Offer:            formatEuro(data.offer),
+		Amount:           formatEuro(amount),
+		Season:           newContractSeason(c.Season),
+		Creditor:         c.SEPA.CreditorName,
+		CreditorID:       c.SEPA.CreditorID,
+		MandateReference: c.Season.mandateReference(bieterID),
+	}
+
+	var renderErr error
+	text := func(s string) string {
+		rendered, err := ct.render(s, cd)
+		if err != nil && renderErr == nil {
+			renderErr = err
+		}
+		return rendered
+	}
+
 	// TODO: Remove
 	//m.SetBorder(true)
 
@@ -21,13 +70,8 @@ func Bietervertrag(domain string, bieterID string, headerImage string, data pdfD
 	m.Row(20, func() {
 		// Adresse
 		m.Col(6, func() {
-			for i, line := range [...]string{
-				"Solidarische Landwirtschaft Baarfood e. V",
-				"Neckarstrasse 120",
-				"78056 Villingen-Schwenningen",
-				"www.baarfood.de",
-			} {
-				m.Text(line, props.Text{
+			for i, line := range ct.Header {
+				m.Text(text(line), props.Text{
 					Size: 10,
 					Top:  float64(i) * 3.5,
 				})
@@ -36,7 +80,9 @@ func Bietervertrag(domain string, bieterID string, headerImage string, data pdfD
 
 		// Baarcode
 		m.Col(3, func() {
-			m.QrCode(fmt.Sprintf("%s/bieter/%s", domain, bieterID))
+			if data.token != "" {
+				m.QrCode(fmt.Sprintf("%s/bieter/%s", c.Domain, data.token))
+			}
 		})
 
 		// Image
@@ -54,7 +100,7 @@ func Bietervertrag(domain string, bieterID string, headerImage string, data pdfD
 	// Gemüsevertrag
 	m.Row(15, func() {
 		m.Col(12, func() {
-			m.Text("Gemüsevertrag", props.Text{
+			m.Text(text(ct.Contract.Title), props.Text{
 				Size:  14,
 				Style: consts.Bold,
 				Align: consts.Center,
@@ -64,55 +110,9 @@ func Bietervertrag(domain string, bieterID string, headerImage string, data pdfD
 	})
 
 	// Vertragstext
-	m.Row(60, func() {
-		m.Col(12, func() {
-			m.Text(fmt.Sprintf(`
-				Ich, %s (E-Mail: %s ), bin Mitglied im des Vereins Solidarische Landwirtschaft Baarfood e.V. 
-				und möchte im Gemüsejahr 2022/23 (April 2022 – März 2023) einen Gemüseanteil beziehen.`, data.Name, data.Mail),
-			)
-			m.Text(
-				`Nach erfolgreicher Bieterrunde schließe ich mit dem Verein Solidarische Landwirtschaft 
-				Baarfood e.V. diesen Gemüsevertrag ab.`,
-				props.Text{
-					Top: 10,
-				},
-			)
-
-			m.Text(
-				`Die Gemüsevertrag gilt von April 2022 bis März 2023 (=12 Monate). 
-				Ich kann mein Gemüse wöchentlich an einer vorher festgelegten Verteilstelle abholen. 
-				Ich respektiere die in den Verteilstellen genannten Anteilsmengen und Abholfristen. 
-				Ich habe keinen Anspruch auf eine bestimmte Menge und Qualität der Produkte. 
-				Sollte es mir vorübergehend nicht möglich sein, meinen Pflichten (Abholung) nach zu kommen, 
-				so sorge ich selbst in diesem Zeitraum für einen Ersatz. Im Falle einer Urlaubsvertretung weise 
-				ich persönlich in die Abholmodalitäten ein. Ein finanzieller Ausgleich wird privat organisiert. 
-				Die endgültige Abgabe meines Anteils im laufenden Jahr ist nur möglich, wenn ein anderes 
-				Vereinsmitglied, das bisher keinen Ernteanteil bezieht, oder ein neues Mitglied, den 
-				oben genannten monatlichen finanziellen Beitrag für die verbleibenden Monate übernimmt. 
-				Erst ab diesem Zeitpunkt erfolgt der Lastschrifteinzug von diesem neuen Mitglied.`,
-				props.Text{
-					Top: 20,
-					// Style: consts.Italic,
-				},
-			)
-		})
-	})
-
-	// Verteilstelle
-	m.Row(5, func() {
-		m.Col(12, func() {
-			m.Text(
-				fmt.Sprintf(`Ich hole meinen Antreil in der Verteilstelle in %s.`, data.Verteilstelle),
-			)
-		})
-	})
-
-	// Abbuchung
-	m.Row(5, func() {
-		m.Col(12, func() {
-			m.Text(fmt.Sprintf(`Die Abbuchung meines Beitrages für den Ernteanteil erfolgt von April 2022 bis März 2023 %s.`, data.Abbuchung))
-		})
-	})
+	for _, paragraph := range ct.Contract.Paragraphs {
+		pdfParagraph(m, text(paragraph))
+	}
 
 	// Datum Unterschrift
 	m.Row(20, func() {
@@ -146,7 +146,7 @@ func Bietervertrag(domain string, bieterID string, headerImage string, data pdfD
 	// SEPA
 	m.Row(15, func() {
 		m.Col(12, func() {
-			m.Text("SEPA Lastschriftmandat", props.Text{
+			m.Text(text(ct.SEPA.Title), props.Text{
 				Size:  14,
 				Style: consts.Bold,
 				Align: consts.Center,
@@ -162,7 +162,7 @@ func Bietervertrag(domain string, bieterID string, headerImage string, data pdfD
 
 		})
 		m.Col(12, func() {
-			m.Text(`DE62ZZZ00001997635`, props.Text{
+			m.Text(c.SEPA.CreditorID, props.Text{
 				Style: consts.Bold,
 			})
 		})
@@ -174,7 +174,7 @@ func Bietervertrag(domain string, bieterID string, headerImage string, data pdfD
 			m.Text(`Mandatsreferenz: `)
 		})
 		m.Col(12, func() {
-			m.Text(fmt.Sprintf(` 22%s`, bieterID), props.Text{
+			m.Text(" "+c.Season.mandateReference(bieterID), props.Text{
 				Style: consts.Bold,
 			})
 		})
@@ -183,61 +183,26 @@ func Bietervertrag(domain string, bieterID string, headerImage string, data pdfD
 	// Abbuchung
 	m.Row(6, func() {
 		m.Col(12, func() {
-			if data.Abbuchung == 1 {
-				m.Text("Die Abbuchung erfolgt am 1. April 2022")
+			if data.Abbuchung == abbuchungYearly {
+				m.Text(text(ct.SEPA.Yearly))
 			} else {
-				m.Text("Die Abbuchung erfolgt am ersten Werktag eines Monats von April 2022 bis März 2023")
+				m.Text(text(ct.SEPA.Monthly))
 			}
 		})
 	})
 
 	m.Row(6, func() {
 		m.Col(12, func() {
-			// if data == 1 {
-			// 	m.Text("Die Abbuchung erfolgt am 1. April 2022")
-			// } else {
-			// 	m.Text("Die Abbuchung erfolgt am ersten Werktag eines Monats von April 2022 bis März 2023")
-			// }
-			betrag := data.offer
-			if data.Abbuchung == 1 {
-				betrag *= 12
-			}
-			euro := betrag / 100
-			cent := betrag % 100
-			m.Text(fmt.Sprintf("Der Betrag lautet: %d,%02d €", euro, cent), props.Text{
+			m.Text(text(ct.SEPA.Amount), props.Text{
 				Style: consts.Bold,
 			})
 		})
 	})
-	// Sepa-Text
-	m.Row(30, func() {
-		m.Col(12, func() {
-			m.Text(`
-			Ich ermächtige den Verein Solidarische Landwirtschaft Baarfood e.V. 
-			Lastschriften von meinem Konto einzuziehen. Zugleich weise ich mein 
-			Kreditinstitut an, die von Solidarische Landwirtschaft Baarfood e.V. 
-			auf mein Konto gezogenen Lastschriften einzulösen.
-			`,
-			)
-
-			m.Text(
-				`Ich kann innerhalb von acht Wochen, beginnend mit dem Belastungsdatum,
-				die Erstattung des belasteten Betrages verlangen. Es gelten dabei die
-				mit meinem Kreditinstitut vereinbarten Bedingungen.
-				`,
-				props.Text{
-					Top: 12,
-				},
-			)
 
-			m.Text(
-				`Ist eine Abbuchung nicht möglich, so geht die Rückbuchungsgebühr zu meinen Lasten.`,
-				props.Text{
-					Top: 20,
-				},
-			)
-		})
-	})
+	// Sepa-Text
+	for _, paragraph := range ct.SEPA.Paragraphs {
+		pdfParagraph(m, text(paragraph))
+	}
 
 	m.Row(10, func() {
 		m.Col(12, func() {
@@ -289,44 +254,28 @@ func Bietervertrag(domain string, bieterID string, headerImage string, data pdfD
 		})
 	})
 
-	pdfile, err := m.Output()
-	if err != nil {
-		return nil, fmt.Errorf("creating pdf: %w", err)
+	if renderErr != nil {
+		return fmt.Errorf("rendering contract template: %w", renderErr)
 	}
+	return nil
+}
 
-	return &pdfile, nil
+// pdfParagraph adds a row with a text over the full width. The height of the row
+// is estimated from the length of the text.
+func pdfParagraph(m pdf.Maroto, text string) {
+	lines := utf8.RuneCountInString(text)/pdfCharsPerLine + 1
+	m.Row(float64(lines)*pdfLineHeight+2, func() {
+		m.Col(12, func() {
+			m.Text(text)
+		})
+	})
 }
 
 type pdfData struct {
-	Name          string        `json:"name"`
-	Mail          string        `json:"mail"`
-	Verteilstelle verteilstelle `json:"verteilstelle"`
-	Abbuchung     abbuchung     `json:"abbuchung"`
-	Kontoinhaber  string        `json:"kontoinhaber"`
-	Adresse       string        `json:"adresse"`
-	IBAN          string        `json:"IBAN"`
+	bieterData
 	offer         int
-}
+	verteilstelle Verteilstelle
 
-type verteilstelle int
-
-func (v verteilstelle) String() string {
-	switch v {
-	case 1:
-		return "Villingen"
-	case 2:
-		return "Schwenningen"
-	case 3:
-		return "Überauchen (Acker)"
-	}
-	return "UNGÜLTIG"
-}
-
-type abbuchung int
-
-func (a abbuchung) String() string {
-	if a == 1 {
-		return "Jährlich"
-	}
-	return "Monatlich"
+	// token is the access token for the link in the qr code.
+	token string
 }
@@ -0,0 +1,191 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+)
+
+// bulkWorkers is the number of contracts, that are rendered at the same time.
+var bulkWorkers = runtime.NumCPU()
+
+// contractJob is the data for the contract of one bieter.
+type contractJob struct {
+	bieterID string
+	data     pdfData
+}
+
+// contractJobs returns the data for the contracts of all bieters sorted by
+// name. If verteilstelleID is not 0, only the bieters of this verteilstelle
+// are used.
+//
+// Bieters with incomplete data are skipped. They are returned as problems.
+func (db *Database) contractJobs(verteilstelleID int) ([]contractJob, []string) {
+	db.RLock()
+	defer db.RUnlock()
+
+	var jobs []contractJob
+	var problems []string
+	for id, payload := range db.bieter {
+		var data pdfData
+		if err := json.Unmarshal(payload, &data.bieterData); err != nil {
+			problems = append(problems, fmt.Sprintf("Bieter %s: Ungültige Daten", id))
+			continue
+		}
+
+		if verteilstelleID != 0 && int(data.Verteilstelle) != verteilstelleID {
+			continue
+		}
+
+		if err := data.validateComplete(); err != nil {
+			problems = append(problems, fmt.Sprintf("Bieter %s (%s): %v", id, data.Name, err))
+			continue
+		}
+
+		data.offer = db.offer[id]
+		data.token = db.bieterToken[id]
+		data.verteilstelle = db.verteilstellen[int(data.Verteilstelle)]
+		jobs = append(jobs, contractJob{bieterID: id, data: data})
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i].data.Name == jobs[j].data.Name {
+			return jobs[i].bieterID < jobs[j].bieterID
+		}
+		return jobs[i].data.Name < jobs[j].data.Name
+	})
+	sort.Strings(problems)
+	return jobs, problems
+}
+
+// renderContracts renders the contracts and calls write for each contract in
+// the order of jobs.
+//
+// bulkWorkers contracts are rendered concurrently. Each contract is written as
+// soon as it and all contracts before it are ready.
+func renderContracts(ctx context.Context, jobs []contractJob, render func(contractJob) (*bytes.Buffer, error), write func(contractJob, *bytes.Buffer) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		pdf *bytes.Buffer
+		err error
+	}
+
+	results := make([]chan result, len(jobs))
+	for i := range results {
+		results[i] = make(chan result, 1)
+	}
+
+	// sem limits the number of contracts, that are rendered but not yet
+	// written. Since the jobs are started in order, the next job to write is
+	// always started.
+	sem := make(chan struct{}, bulkWorkers)
+	go func() {
+		for i, job := range jobs {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			go func(i int, job contractJob) {
+				buf, err := render(job)
+				results[i] <- result{buf, err}
+			}(i, job)
+		}
+	}()
+
+	for i, job := range jobs {
+		var r result
+		select {
+		case r = <-results[i]:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		<-sem
+
+		if r.err != nil {
+			return fmt.Errorf("rendering contract of bieter %s: %w", job.bieterID, r.err)
+		}
+
+		if err := write(job, r.pdf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeContractsZIP renders the contracts and writes them as zip file to w.
+//
+// The contracts are written in the order of jobs. The problems are written to
+// the file fehler.txt.
+func writeContractsZIP(ctx context.Context, w io.Writer, jobs []contractJob, problems []string, render func(contractJob) (*bytes.Buffer, error)) error {
+	zw := zip.NewWriter(w)
+	err := renderContracts(ctx, jobs, render, func(job contractJob, pdf *bytes.Buffer) error {
+		f, err := zw.Create(fmt.Sprintf("bietervertrag-%s.pdf", job.bieterID))
+		if err != nil {
+			return fmt.Errorf("creating zip entry: %w", err)
+		}
+
+		if _, err := pdf.WriteTo(f); err != nil {
+			return fmt.Errorf("writing contract of bieter %s: %w", job.bieterID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(problems) > 0 {
+		f, err := zw.Create("fehler.txt")
+		if err != nil {
+			return fmt.Errorf("creating zip entry: %w", err)
+		}
+
+		if _, err := io.WriteString(f, strings.Join(problems, "\n")+"\n"); err != nil {
+			return fmt.Errorf("writing problems: %w", err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("closing zip: %w", err)
+	}
+	return nil
+}
+
+// BietervertragMerged creates one pdf with the contracts of all jobs.
+//
+// The contracts are rendered concurrently like in writeContractsZIP and merged
+// afterwards.
+func BietervertragMerged(ctx context.Context, jobs []contractJob, render func(contractJob) (*bytes.Buffer, error)) (*bytes.Buffer, error) {
+	if len(jobs) == 0 {
+		return nil, validationError{"Es gibt keine vollständigen Bieter"}
+	}
+
+	pdfs := make([]io.ReadSeeker, 0, len(jobs))
+	err := renderContracts(ctx, jobs, render, func(_ contractJob, pdf *bytes.Buffer) error {
+		pdfs = append(pdfs, bytes.NewReader(pdf.Bytes()))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Without this, pdfcpu writes its config to the home directory.
+	api.DisableConfigDir()
+
+	var merged bytes.Buffer
+	if err := api.MergeRaw(pdfs, &merged, nil); err != nil {
+		return nil, fmt.Errorf("merging pdfs: %w", err)
+	}
+	return &merged, nil
+}
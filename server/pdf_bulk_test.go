@@ -0,0 +1,120 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+)
+
+func bulkTestDB(t *testing.T, bieter int) *Database {
+	t.Helper()
+
+	events := []string{
+		`{"type":"verteilstelle","payload":{"id":1,"name":"Villingen"}}`,
+		`{"type":"verteilstelle","payload":{"id":2,"name":"Schwenningen"}}`,
+		`{"type":"update","payload":{"id":"incomplete","payload":{"name":"Ohne Daten","verteilstelle":1}}}`,
+	}
+	for i := 0; i < bieter; i++ {
+		events = append(events,
+			fmt.Sprintf(`{"type":"update","payload":{"id":"%d","payload":{"name":"Bieter %03d","mail":"b%d@example.com","verteilstelle":%d,"adresse":"Hauptstraße %d","iban":"DE02120300000000202051"}}}`, i, i, i, i%2+1, i),
+			fmt.Sprintf(`{"type":"offer","payload":{"id":"%d","offer":%d}}`, i, 5000+i),
+		)
+	}
+
+	db, err := NewDB(NewMemoryStore(events...))
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	return db
+}
+
+func TestContractsZIP(t *testing.T) {
+	db := bulkTestDB(t, 20)
+
+	ct, err := parseContractTemplate(defaultContract)
+	if err != nil {
+		t.Fatalf("parsing template: %v", err)
+	}
+
+	img, err := os.ReadFile("../static/images/pdf_header_image.png")
+	if err != nil {
+		t.Fatalf("reading header image: %v", err)
+	}
+	headerImage := base64.StdEncoding.EncodeToString(img)
+
+	jobs, problems := db.contractJobs(1)
+	if len(jobs) != 10 || len(problems) != 1 {
+		t.Fatalf("got %d jobs and %d problems, expected 10 and 1", len(jobs), len(problems))
+	}
+
+	render := func(job contractJob) (*bytes.Buffer, error) {
+		return Bietervertrag(DefaultConfig(), ct, job.bieterID, headerImage, job.data)
+	}
+
+	var buf bytes.Buffer
+	if err := writeContractsZIP(context.Background(), &buf, jobs, problems, render); err != nil {
+		t.Fatalf("writeContractsZIP: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("reading zip: %v", err)
+	}
+
+	if len(zr.File) != 11 {
+		t.Fatalf("zip has %d files, expected 11", len(zr.File))
+	}
+
+	for i, job := range jobs {
+		if expect := "bietervertrag-" + job.bieterID + ".pdf"; zr.File[i].Name != expect {
+			t.Errorf("file %d is %s, expected %s", i, zr.File[i].Name, expect)
+		}
+	}
+
+	f, err := zr.File[10].Open()
+	if err != nil {
+		t.Fatalf("open fehler.txt: %v", err)
+	}
+	content, _ := io.ReadAll(f)
+	if !strings.Contains(string(content), "Ohne Daten") {
+		t.Errorf("fehler.txt does not contain the incomplete bieter: %s", content)
+	}
+
+	merged, err := BietervertragMerged(context.Background(), jobs, render)
+	if err != nil {
+		t.Fatalf("BietervertragMerged: %v", err)
+	}
+
+	pages, err := api.PageCount(bytes.NewReader(merged.Bytes()), nil)
+	if err != nil {
+		t.Fatalf("reading merged pdf: %v", err)
+	}
+	if pages != len(jobs) {
+		t.Errorf("merged pdf has %d pages, expected %d", pages, len(jobs))
+	}
+}
+
+func TestContractsZIPRenderError(t *testing.T) {
+	db := bulkTestDB(t, 20)
+	jobs, _ := db.contractJobs(0)
+
+	render := func(job contractJob) (*bytes.Buffer, error) {
+		if job.bieterID == "5" {
+			return nil, fmt.Errorf("broken")
+		}
+		return bytes.NewBufferString("pdf"), nil
+	}
+
+	err := writeContractsZIP(context.Background(), io.Discard, jobs, nil, render)
+	if err == nil || !strings.Contains(err.Error(), "bieter 5") {
+		t.Errorf("got error %v, expected error for bieter 5", err)
+	}
+}
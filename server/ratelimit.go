@@ -0,0 +1,181 @@
+package server
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// RateLimitConfig limits failed admin logins and bieter lookups per ip.
+type RateLimitConfig struct {
+	// Failures is the number of failed attempts, before a client has to
+	// wait. 0 disables the limit.
+	Failures int `toml:"failures"`
+
+	// Delay is the wait time in seconds after the first failure over the
+	// limit. It doubles with each further failure up to MaxDelay.
+	Delay    int `toml:"delay"`
+	MaxDelay int `toml:"max_delay"`
+
+	// Alert is the number of failed attempts, after which a warning is
+	// logged.
+	Alert int `toml:"alert"`
+
+	// Reset is the time in seconds after the last failure, when the failures
+	// of a client are forgotten.
+	Reset int `toml:"reset"`
+
+	// TrustProxy uses the last address of the X-Forwarded-For header as
+	// client ip. Only set it, if the server runs behind a reverse proxy.
+	TrustProxy bool `toml:"trust_proxy"`
+}
+
+// failureLimiter counts failed attempts per client and blocks clients with
+// too many failures with an exponential backoff.
+type failureLimiter struct {
+	mu      sync.Mutex
+	config  RateLimitConfig
+	clients map[string]*failureRecord
+	pruned  time.Time
+
+	// now can be replaced in tests.
+	now func() time.Time
+}
+
+type failureRecord struct {
+	failures     int
+	last         time.Time
+	blockedUntil time.Time
+}
+
+func newFailureLimiter(c RateLimitConfig) *failureLimiter {
+	return &failureLimiter{
+		config:  c,
+		clients: make(map[string]*failureRecord),
+		now:     time.Now,
+	}
+}
+
+// blocked returns the remaining wait time, if the client is blocked.
+func (l *failureLimiter) blocked(ip string) (time.Duration, bool) {
+	if l.config.Failures <= 0 {
+		return 0, false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	record, ok := l.clients[ip]
+	if !ok {
+		return 0, false
+	}
+
+	wait := record.blockedUntil.Sub(l.now())
+	return wait, wait > 0
+}
+
+// fail records a failed attempt. kind describes the attempt for the log.
+func (l *failureLimiter) fail(ip string, kind string) {
+	if l.config.Failures <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	record, ok := l.clients[ip]
+	if !ok || now.Sub(record.last) > time.Duration(l.config.Reset)*time.Second {
+		record = &failureRecord{}
+		l.clients[ip] = record
+	}
+
+	record.failures++
+	record.last = now
+
+	over := record.failures - l.config.Failures
+	if over <= 0 {
+		return
+	}
+
+	delay := time.Duration(l.config.Delay) * time.Second
+	maxDelay := time.Duration(l.config.MaxDelay) * time.Second
+	for i := 1; i < over && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	record.blockedUntil = now.Add(delay)
+
+	if over == 1 {
+		log.Printf("Warning: %s blocked after %d failed attempts (%s)", ip, record.failures, kind)
+	}
+
+	if record.failures == l.config.Alert {
+		log.Printf("Alert: %d failed attempts from %s (%s)", record.failures, ip, kind)
+	}
+}
+
+// prune removes clients, whose failures are forgotten. It runs at most once a
+// minute.
+//
+// Has to be called with the lock.
+func (l *failureLimiter) prune(now time.Time) {
+	if now.Sub(l.pruned) < time.Minute {
+		return
+	}
+	l.pruned = now
+
+	reset := time.Duration(l.config.Reset) * time.Second
+	for ip, record := range l.clients {
+		if now.Sub(record.last) > reset && !now.Before(record.blockedUntil) {
+			delete(l.clients, ip)
+		}
+	}
+}
+
+// middleware rejects api requests from blocked clients.
+func (l *failureLimiter) middleware(config Config) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, pathPrefixAPI) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ip := clientIP(r, config.RateLimit)
+			if wait, blocked := l.blocked(ip); blocked {
+				w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+				handleError(w, clientError{msg: "Zu viele fehlgeschlagene Versuche. Bitte später erneut versuchen", status: http.StatusTooManyRequests})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIP returns the ip of the client.
+func clientIP(r *http.Request, c RateLimitConfig) string {
+	if c.TrustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			parts := strings.Split(forwarded, ",")
+			return strings.TrimSpace(parts[len(parts)-1])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
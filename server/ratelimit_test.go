@@ -0,0 +1,84 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFailureLimiter(t *testing.T) {
+	now := time.Date(2022, time.April, 1, 12, 0, 0, 0, time.UTC)
+	l := newFailureLimiter(RateLimitConfig{Failures: 3, Delay: 1, MaxDelay: 4, Reset: 60})
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		l.fail("1.2.3.4", "test")
+	}
+	if _, blocked := l.blocked("1.2.3.4"); blocked {
+		t.Fatalf("client is blocked before the limit")
+	}
+
+	for i, expect := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		l.fail("1.2.3.4", "test")
+		if wait, _ := l.blocked("1.2.3.4"); wait != expect {
+			t.Errorf("failure %d: got wait %v, expected %v", i+4, wait, expect)
+		}
+	}
+
+	if _, blocked := l.blocked("5.6.7.8"); blocked {
+		t.Errorf("other client is blocked")
+	}
+
+	now = now.Add(2 * time.Minute)
+	l.fail("1.2.3.4", "test")
+	if _, blocked := l.blocked("1.2.3.4"); blocked {
+		t.Errorf("client is blocked after the failures were reset")
+	}
+	l = newFailureLimiter(DefaultConfig().RateLimit)
+	for i := 0; i < 11; i++ {
+		l.fail("1.2.3.4", "test")
+	}
+	if _, blocked := l.blocked("1.2.3.4"); !blocked {
+		t.Errorf("client is not blocked with the default config")
+	}
+
+	l = newFailureLimiter(RateLimitConfig{Failures: 0})
+	for i := 0; i < 100; i++ {
+		l.fail("1.2.3.4", "test")
+	}
+	if _, blocked := l.blocked("1.2.3.4"); blocked {
+		t.Errorf("client is blocked with failures 0")
+	}
+}
+
+func TestLimiterMiddleware(t *testing.T) {
+	config := DefaultConfig()
+	config.RateLimit.Failures = 2
+
+	l := newFailureLimiter(config.RateLimit)
+	handler := l.middleware(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	request := func(path string) int {
+		r := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := request("/api/state"); code != 200 {
+		t.Errorf("got status %d before failures, expected 200", code)
+	}
+
+	for i := 0; i < 3; i++ {
+		l.fail("192.0.2.1", "test")
+	}
+
+	if code := request("/api/state"); code != http.StatusTooManyRequests {
+		t.Errorf("got status %d after failures, expected 429", code)
+	}
+
+	if code := request("/static/style.css"); code != 200 {
+		t.Errorf("got status %d for a static file, expected 200", code)
+	}
+}
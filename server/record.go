@@ -0,0 +1,102 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// recordTimeFormat is the format of the time in the event records. It is the
+// local time of the server.
+const recordTimeFormat = "2006-01-02 15:04:05"
+
+// EventRecord is an event with its metadata, as it is saved in the event
+// store.
+type EventRecord struct {
+	// Seq is the number of the event in the store, starting with 1. Old
+	// records without a number get their position.
+	Seq  int        `json:"seq,omitempty"`
+	Type string     `json:"type"`
+	Time recordTime `json:"time"`
+
+	// User and Role are the admin user, that created the event. They are
+	// empty, if a bieter changed the own data.
+	User string `json:"user,omitempty"`
+	Role Role   `json:"role,omitempty"`
+
+	// IP and RequestID are from the http request, that created the event.
+	IP        string `json:"ip,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+
+	Payload json.RawMessage `json:"payload"`
+}
+
+// newEventRecord creates the record of an event.
+func newEventRecord(e Event, seq int, user User) (EventRecord, error) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return EventRecord{}, fmt.Errorf("encoding payload: %w", err)
+	}
+
+	return EventRecord{
+		Seq:       seq,
+		Type:      e.Name(),
+		Time:      recordTime{time.Now()},
+		User:      user.Name,
+		Role:      user.Role,
+		IP:        user.IP,
+		RequestID: user.RequestID,
+		Payload:   payload,
+	}, nil
+}
+
+// decodeRecord decodes one record of the event store. position is the number
+// of the record in the store, that is used as seq for old records.
+func decodeRecord(bs []byte, position int) (EventRecord, error) {
+	var record EventRecord
+	if err := json.Unmarshal(bs, &record); err != nil {
+		return EventRecord{}, fmt.Errorf("decoding event: %w", err)
+	}
+
+	if record.Seq == 0 {
+		record.Seq = position
+	}
+	return record, nil
+}
+
+// event returns the decoded event of the record.
+func (r EventRecord) event() (Event, error) {
+	event := getEvent(r.Type)
+	if event == nil {
+		return nil, fmt.Errorf("Unknown event %q, payload %q", r.Type, r.Payload)
+	}
+
+	if err := json.Unmarshal(r.Payload, &event); err != nil {
+		return nil, fmt.Errorf("loading event %q: %w", r.Type, err)
+	}
+	return event, nil
+}
+
+// recordTime is a time, that is encoded in the recordTimeFormat. The methods
+// of time.Time would use RFC 3339.
+type recordTime struct {
+	time.Time
+}
+
+func (t recordTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Format(recordTimeFormat))
+}
+
+func (t *recordTime) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("decoding time: %w", err)
+	}
+
+	parsed, err := time.ParseInLocation(recordTimeFormat, text, time.Local)
+	if err != nil {
+		return fmt.Errorf("invalid time %q: %w", text, err)
+	}
+	t.Time = parsed
+	return nil
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecodeRecord(t *testing.T) {
+	old := `{"type":"update","time":"2022-03-01 18:30:00","payload":{"id":"1","payload":{"name":"Hugo"}}}`
+
+	record, err := decodeRecord([]byte(old), 7)
+	if err != nil {
+		t.Fatalf("decodeRecord: %v", err)
+	}
+
+	if record.Seq != 7 {
+		t.Errorf("got seq %d, expected the position 7", record.Seq)
+	}
+
+	expect := time.Date(2022, time.March, 1, 18, 30, 0, 0, time.Local)
+	if !record.Time.Equal(expect) {
+		t.Errorf("got time %v, expected %v", record.Time, expect)
+	}
+
+	if record.User != "" {
+		t.Errorf("got user %q for an old record", record.User)
+	}
+}
+
+func TestEventRecordMetadata(t *testing.T) {
+	store := NewMemoryStore(
+		`{"type":"update","time":"2022-03-01 18:30:00","payload":{"id":"1","payload":{"name":"Hugo"}}}`,
+	)
+	db, err := NewDB(store)
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+
+	user := User{Name: "erika", Role: roleBoard, IP: "192.0.2.1", RequestID: "abc"}
+	if err := db.DeleteBieter("1", user); err != nil {
+		t.Fatalf("DeleteBieter: %v", err)
+	}
+
+	var records []EventRecord
+	err = store.Iterate(func(bs []byte) error {
+		record, err := decodeRecord(bs, len(records)+1)
+		if err != nil {
+			return err
+		}
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("reading records: %v", err)
+	}
+
+	got := records[1]
+	if got.Seq != 2 || got.Type != "delete" || got.User != "erika" || got.Role != roleBoard || got.IP != "192.0.2.1" || got.RequestID != "abc" {
+		t.Errorf("got record %+v", got)
+	}
+
+	if time.Since(got.Time.Time) > time.Minute {
+		t.Errorf("got time %v, expected now", got.Time)
+	}
+
+	if _, err := NewDB(store); err != nil {
+		t.Errorf("loading the records again: %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// errReplayLimit stops the iteration of the events, when the ReplayLimit is
+// reached.
+var errReplayLimit = errors.New("replay limit reached")
+
+// ReplayLimit stops the replay of the events at a point in time. The zero
+// value replays all events.
+type ReplayLimit struct {
+	// Seq is the number of the last event, that is replayed.
+	Seq int
+
+	// Before is the time, when the replay stops. Only events before this time
+	// are replayed.
+	Before time.Time
+}
+
+// reached returns true, if the record is after the limit.
+func (l ReplayLimit) reached(r EventRecord) bool {
+	if l.Seq > 0 && r.Seq > l.Seq {
+		return true
+	}
+	return !l.Before.IsZero() && !r.Time.Before(l.Before)
+}
+
+// parseReplayLimit parses the value of the at parameter.
+//
+// A number is the sequence number of the last event. A date is the end of the
+// day and a time in the format of the records is the moment before that
+// time.
+func parseReplayLimit(value string) (ReplayLimit, error) {
+	if seq, err := strconv.Atoi(value); err == nil {
+		if seq < 1 {
+			return ReplayLimit{}, clientError{msg: fmt.Sprintf("Ungültige Eventnummer %d", seq)}
+		}
+		return ReplayLimit{Seq: seq}, nil
+	}
+
+	before, err := parseHistoryTime(value, true)
+	if err != nil {
+		return ReplayLimit{}, err
+	}
+	return ReplayLimit{Before: before}, nil
+}
+
+// At returns the database, as it was at the limit.
+//
+// The events are replayed from the event store without the snapshot. The
+// returned database has no event store, so it can only be read.
+func (db *Database) At(limit ReplayLimit) (*Database, error) {
+	at := emptyDatabase()
+	if err := at.replay(db.store.Iterate, 0, limit); err != nil {
+		return nil, fmt.Errorf("replaying events: %w", err)
+	}
+	return at, nil
+}
+
+// Replay writes the bieters, as they were at a point in time, to a csv or xlsx
+// file. at is a sequence number, a date or a time. If outFile is empty, the
+// csv is written to stdout.
+//
+// The export columns and the sort order are taken from the config.
+func Replay(configFile, dbFile, at, outFile string) error {
+	limit, err := parseReplayLimit(at)
+	if err != nil {
+		return err
+	}
+
+	config, db, err := openConfigDB(configFile, dbFile)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	past, err := db.At(limit)
+	if err != nil {
+		return err
+	}
+
+	table, err := past.Export(config, config.Export.Columns, config.Export.Sort)
+	if err != nil {
+		return fmt.Errorf("exporting bieter: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if filepath.Ext(outFile) == ".xlsx" {
+		err = table.writeXLSX(&buf)
+	} else {
+		err = table.writeCSV(&buf)
+	}
+	if err != nil {
+		return fmt.Errorf("writing bieter: %w", err)
+	}
+
+	if outFile == "" {
+		os.Stdout.Write(buf.Bytes())
+	} else if err := os.WriteFile(outFile, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("writing output file: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Stand nach Event %d: %s, Runde %d, %d Bieter\n", past.events, past.State(), past.Round(), len(past.bieter))
+	return nil
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"strings"
+	"testing"
+)
+
+const replayTestEvents = `
+{"type":"update","time":"2022-03-01 18:30:00","payload":{"id":"1","payload":{"name":"Hugo"}}}
+{"type":"state","time":"2022-03-02 10:00:00","payload":{"state":3}}
+{"type":"offer","time":"2022-03-03 12:00:00","payload":{"id":"1","offer":5000}}
+{"type":"delete","time":"2022-03-04 09:00:00","payload":{"id":"1"}}
+`
+
+func TestReplayLimit(t *testing.T) {
+	for _, tt := range []struct {
+		at     string
+		events int
+		offer  int
+		exists bool
+	}{
+		{"2", 2, 0, true},
+		{"2022-03-03", 3, 5000, true},
+		{"2022-03-03 12:00:00", 2, 0, true},
+		{"2022-03-04 09:00:01", 4, 0, false},
+	} {
+		t.Run(tt.at, func(t *testing.T) {
+			limit, err := parseReplayLimit(tt.at)
+			if err != nil {
+				t.Fatalf("parseReplayLimit: %v", err)
+			}
+
+			db, err := loadDatabase(strings.NewReader(replayTestEvents), limit)
+			if err != nil {
+				t.Fatalf("loadDatabase: %v", err)
+			}
+
+			if db.events != tt.events {
+				t.Errorf("replayed %d events, expected %d", db.events, tt.events)
+			}
+
+			if got := db.Offer("1"); got != tt.offer {
+				t.Errorf("got offer %d, expected %d", got, tt.offer)
+			}
+
+			if _, ok := db.Bieter("1"); ok != tt.exists {
+				t.Errorf("bieter exists: %t, expected %t", ok, tt.exists)
+			}
+		})
+	}
+
+	if _, err := parseReplayLimit("gestern"); err == nil {
+		t.Errorf("invalid value was accepted")
+	}
+}
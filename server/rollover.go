@@ -0,0 +1,198 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Bieter selections for the rollover.
+const (
+	rolloverWithOffer = "offer"
+	rolloverAll       = "all"
+)
+
+// Rollover archives the database and starts a new season.
+//
+// The new database contains the verteilstellen and the selected bieters with
+// their ids but without offers. The bieters get new access tokens. With
+// keepTokens, the old tokens are used, so printed QR codes still work. selection is "offer" for all bieters with an
+// offer in the current round or "all". If ids is not empty, only these bieters
+// are used.
+//
+// The old events are saved read only in the archive directory with the start
+// of the season as name. It fails, if the database file is used by the running
+// server.
+func Rollover(configFile, dbFile string, selection string, ids []string, keepTokens bool) error {
+	config, db, err := openConfigDB(configFile, dbFile)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	compacter, ok := db.store.(Compacter)
+	if !ok {
+		return fmt.Errorf("event store does not support replacing the events")
+	}
+
+	selected, err := db.rolloverSelection(selection, ids)
+	if err != nil {
+		return err
+	}
+
+	events, err := db.rolloverEvents(selected, keepTokens)
+	if err != nil {
+		return err
+	}
+
+	name := config.Season.Start.Format("2006-01")
+	archive, err := archiveEvents(db.store, config.ArchiveDir, name)
+	if err != nil {
+		return fmt.Errorf("archiving season %s: %w", name, err)
+	}
+
+	records := make([][]byte, len(events))
+	for i, e := range events {
+		bs, err := encodeEvent(e, i+1, systemUser)
+		if err != nil {
+			return fmt.Errorf("encoding event: %w", err)
+		}
+		records[i] = bs
+	}
+
+	backup, err := compacter.Replace(records)
+	if err != nil {
+		return fmt.Errorf("replacing events: %w", err)
+	}
+
+	fmt.Printf("Archived season %s to %s. Started new season with %d bieters. Backup: %s\n", name, archive, len(selected), backup)
+	fmt.Println("Update the season in the config before starting the server.")
+	if !keepTokens {
+		fmt.Println("All bieters got new access tokens. The contracts have to be printed again.")
+	}
+	return nil
+}
+
+// rolloverSelection returns the sorted ids of the bieters, that are carried
+// into the new season.
+func (db *Database) rolloverSelection(selection string, ids []string) ([]string, error) {
+	db.RLock()
+	defer db.RUnlock()
+
+	var selected []string
+	if len(ids) > 0 {
+		for _, id := range ids {
+			if _, ok := db.bieter[id]; !ok {
+				return nil, fmt.Errorf("bieter %s does not exist", id)
+			}
+			selected = append(selected, id)
+		}
+		sort.Strings(selected)
+		return selected, nil
+	}
+
+	for id := range db.bieter {
+		switch selection {
+		case rolloverAll:
+			selected = append(selected, id)
+
+		case rolloverWithOffer:
+			if db.offer[id] > 0 {
+				selected = append(selected, id)
+			}
+
+		default:
+			return nil, fmt.Errorf("unknown selection %q. Use %q or %q", selection, rolloverWithOffer, rolloverAll)
+		}
+	}
+	sort.Strings(selected)
+	return selected, nil
+}
+
+// rolloverEvents returns the events for the database of the new season.
+func (db *Database) rolloverEvents(bieterIDs []string, keepTokens bool) ([]Event, error) {
+	db.RLock()
+	defer db.RUnlock()
+
+	var events []Event
+	for _, v := range db.sortedVerteilstellen() {
+		events = append(events, eventVerteilstelle{Verteilstelle: v})
+	}
+
+	for _, id := range bieterIDs {
+		token := db.bieterToken[id]
+		if !keepTokens {
+			var err error
+			token, err = newToken()
+			if err != nil {
+				return nil, fmt.Errorf("creating token: %w", err)
+			}
+		}
+		events = append(events, eventUpdate{ID: id, Payload: db.bieter[id], Token: token})
+	}
+
+	return append(events, db.counterEvents()...), nil
+}
+
+// archiveEvents writes all events of the store to dir/name.jsonl and makes the
+// file read only.
+func archiveEvents(store EventStore, dir, name string) (string, error) {
+	file := filepath.Join(dir, name+".jsonl")
+	if _, err := os.Stat(file); !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("archive %s already exists", file)
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("creating archive directory: %w", err)
+	}
+
+	var content []byte
+	err := store.Iterate(func(record []byte) error {
+		content = append(content, record...)
+		content = append(content, '\n')
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("reading events: %w", err)
+	}
+
+	if err := writeFileAtomic(file, content); err != nil {
+		return "", fmt.Errorf("writing archive: %w", err)
+	}
+
+	if err := os.Chmod(file, 0400); err != nil {
+		return "", fmt.Errorf("making archive read only: %w", err)
+	}
+	return file, nil
+}
+
+// LoadArchive loads all archived seasons from dir. The key is the name of the
+// season.
+//
+// The databases have no event store, so they can only be read.
+func LoadArchive(dir string) (map[string]*Database, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	if err != nil {
+		return nil, fmt.Errorf("listing archive: %w", err)
+	}
+
+	archive := make(map[string]*Database, len(files))
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("open archive: %w", err)
+		}
+
+		db, err := loadDatabase(f, ReplayLimit{})
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("loading archive %s: %w", file, err)
+		}
+
+		archive[strings.TrimSuffix(filepath.Base(file), ".jsonl")] = db
+	}
+	return archive, nil
+}
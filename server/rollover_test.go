@@ -0,0 +1,105 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeRolloverSeason writes a config and a database for the rollover tests.
+func writeRolloverSeason(t *testing.T) (configFile, dbFile, archiveDir string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	configFile = filepath.Join(dir, "config.toml")
+	dbFile = filepath.Join(dir, "db.jsonl")
+	archiveDir = filepath.Join(dir, "archive")
+
+	config := fmt.Sprintf("admin_password = \"secret\"\narchive_dir = %q\n", archiveDir)
+	if err := os.WriteFile(configFile, []byte(config), 0600); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+
+	events := strings.Join([]string{
+		`{"type":"verteilstelle","payload":{"id":1,"name":"Villingen"}}`,
+		`{"type":"verteilstelle","payload":{"id":2,"name":"Schwenningen"}}`,
+		`{"type":"verteilstelle-delete","payload":{"id":2}}`,
+		`{"type":"update","payload":{"id":"1","payload":{"name":"Hugo","verteilstelle":1},"token":"old-token"}}`,
+		`{"type":"update","payload":{"id":"2","payload":{"name":"Erika"}}}`,
+		`{"type":"offer","payload":{"id":"1","offer":5000}}`,
+	}, "\n") + "\n"
+	if err := os.WriteFile(dbFile, []byte(events), 0600); err != nil {
+		t.Fatalf("writing db: %v", err)
+	}
+	return configFile, dbFile, archiveDir
+}
+
+func TestRollover(t *testing.T) {
+	configFile, dbFile, archiveDir := writeRolloverSeason(t)
+
+	if err := Rollover(configFile, dbFile, rolloverWithOffer, nil, false); err != nil {
+		t.Fatalf("Rollover: %v", err)
+	}
+
+	_, db, err := openConfigDB(configFile, dbFile)
+	if err != nil {
+		t.Fatalf("open new season: %v", err)
+	}
+	defer db.Close()
+
+	if _, ok := db.Bieter("1"); !ok {
+		t.Errorf("bieter 1 was not carried over")
+	}
+	if _, ok := db.Bieter("2"); ok {
+		t.Errorf("bieter 2 without offer was carried over")
+	}
+	if got := db.Offer("1"); got != 0 {
+		t.Errorf("offer in new season is %d, expected 0", got)
+	}
+	if _, ok := db.Verteilstelle(1); !ok {
+		t.Errorf("verteilstelle was not carried over")
+	}
+	if got := db.nextVerteilstelleID(); got != 3 {
+		t.Errorf("next verteilstelle id is %d, expected 3", got)
+	}
+	if got := db.Token("1"); got == "" || got == "old-token" {
+		t.Errorf("bieter 1 has token %q, expected a new token", got)
+	}
+
+	archive, err := LoadArchive(archiveDir)
+	if err != nil {
+		t.Fatalf("LoadArchive: %v", err)
+	}
+
+	old, ok := archive["2022-04"]
+	if !ok {
+		t.Fatalf("got archive %v, expected season 2022-04", archive)
+	}
+	if got := old.Offer("1"); got != 5000 {
+		t.Errorf("archived offer is %d, expected 5000", got)
+	}
+
+	if err := Rollover(configFile, dbFile, rolloverWithOffer, nil, false); err == nil {
+		t.Errorf("second rollover of the same season did not fail")
+	}
+}
+
+func TestRolloverKeepTokens(t *testing.T) {
+	configFile, dbFile, _ := writeRolloverSeason(t)
+
+	if err := Rollover(configFile, dbFile, rolloverWithOffer, nil, true); err != nil {
+		t.Fatalf("Rollover: %v", err)
+	}
+
+	_, db, err := openConfigDB(configFile, dbFile)
+	if err != nil {
+		t.Fatalf("open new season: %v", err)
+	}
+	defer db.Close()
+
+	if got := db.Token("1"); got != "old-token" {
+		t.Errorf("bieter 1 has token %q, expected old-token", got)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"io/fs"
 	"log"
 	"net/http"
+	"path/filepath"
+	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -17,6 +19,26 @@ type DefaultFiles struct {
 	Static fs.FS
 }
 
+// openConfigDB loads the config and opens the database for a command.
+func openConfigDB(configFile, dbFile string) (Config, *Database, error) {
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		return Config{}, nil, fmt.Errorf("reading config: %w", err)
+	}
+
+	store, err := OpenStore(config, dbFile)
+	if err != nil {
+		return Config{}, nil, fmt.Errorf("open event store: %w", err)
+	}
+
+	db, err := NewDB(store)
+	if err != nil {
+		store.Close()
+		return Config{}, nil, fmt.Errorf("open database: %w", err)
+	}
+	return config, db, nil
+}
+
 // Run starts the server until the context is canceled.
 func Run(ctx context.Context, configFile, dbFile string, defaultFiles DefaultFiles) error {
 	config, err := LoadConfig(configFile)
@@ -24,13 +46,46 @@ func Run(ctx context.Context, configFile, dbFile string, defaultFiles DefaultFil
 		return fmt.Errorf("reading config: %w", err)
 	}
 
-	db, err := NewDB(dbFile)
+	contractFile := config.ContractFile
+	if contractFile == "" {
+		contractFile = filepath.Join(filepath.Dir(configFile), "contract.toml")
+	}
+
+	contract, err := LoadContractTemplate(contractFile)
+	if err != nil {
+		return fmt.Errorf("loading contract template: %w", err)
+	}
+
+	archive, err := LoadArchive(config.ArchiveDir)
+	if err != nil {
+		return fmt.Errorf("loading archive: %w", err)
+	}
+
+	store, err := OpenStore(config, dbFile)
+	if err != nil {
+		return fmt.Errorf("open event store: %w", err)
+	}
+
+	db, err := NewDB(store)
 	if err != nil {
-		return fmt.Errorf("open database file: %w", err)
+		store.Close()
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.seedVerteilstellen(); err != nil {
+		return fmt.Errorf("creating default verteilstellen: %w", err)
 	}
 
+	sessions, err := newSessionStore(time.Duration(config.SessionHours) * time.Hour)
+	if err != nil {
+		return fmt.Errorf("creating session store: %w", err)
+	}
+
+	go runScheduler(ctx, db)
+
 	router := mux.NewRouter()
-	registerHandlers(router, config, db, defaultFiles)
+	registerHandlers(router, config, db, archive, sessions, contract, defaultFiles)
 
 	srv := &http.Server{Addr: config.ListenAddr, Handler: router}
 
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"time"
+)
+
+// scheduleInterval is the time between two checks for due transitions.
+const scheduleInterval = time.Second
+
+// ScheduledState is a state transition, that happens at a given time.
+type ScheduledState struct {
+	ID    int          `json:"id"`
+	State ServiceState `json:"state"`
+	At    time.Time    `json:"at"`
+}
+
+// Schedule returns all pending transitions sorted by time.
+func (db *Database) Schedule() []ScheduledState {
+	db.RLock()
+	defer db.RUnlock()
+
+	return db.sortedSchedule()
+}
+
+func (db *Database) sortedSchedule() []ScheduledState {
+	schedule := make([]ScheduledState, 0, len(db.schedule))
+	for _, s := range db.schedule {
+		schedule = append(schedule, s)
+	}
+
+	sort.Slice(schedule, func(i, j int) bool {
+		if schedule[i].At.Equal(schedule[j].At) {
+			return schedule[i].ID < schedule[j].ID
+		}
+		return schedule[i].At.Before(schedule[j].At)
+	})
+	return schedule
+}
+
+// NextTransition returns the next pending transition. Returns false, if there
+// is none.
+func (db *Database) NextTransition() (ScheduledState, bool) {
+	db.RLock()
+	defer db.RUnlock()
+
+	schedule := db.sortedSchedule()
+	if len(schedule) == 0 {
+		return ScheduledState{}, false
+	}
+	return schedule[0], true
+}
+
+// AddSchedule adds a new transition. It is read from r and returned.
+func (db *Database) AddSchedule(r io.Reader, user User) (ScheduledState, error) {
+	if !user.can(permWrite) {
+		return ScheduledState{}, errForbidden
+	}
+
+	var decoded struct {
+		State int       `json:"state"`
+		At    time.Time `json:"at"`
+	}
+	if err := json.NewDecoder(r).Decode(&decoded); err != nil {
+		return ScheduledState{}, fmt.Errorf("decoding schedule: %w", err)
+	}
+
+	if !decoded.At.After(time.Now()) {
+		return ScheduledState{}, validationError{"Der Zeitpunkt muss in der Zukunft liegen"}
+	}
+
+	for {
+		event, err := newEventSchedule(db.nextScheduleID(), ServiceState(decoded.State), decoded.At)
+		if err != nil {
+			return ScheduledState{}, fmt.Errorf("creating schedule event: %w", err)
+		}
+
+		if err := db.writeEvent(event, user); err != nil {
+			if errors.Is(err, errScheduleIDExists) {
+				continue
+			}
+			return ScheduledState{}, fmt.Errorf("writing schedule event: %w", err)
+		}
+		return event.ScheduledState, nil
+	}
+}
+
+func (db *Database) nextScheduleID() int {
+	db.RLock()
+	defer db.RUnlock()
+
+	return db.lastScheduleID + 1
+}
+
+// DeleteSchedule removes a pending transition.
+func (db *Database) DeleteSchedule(id int, user User) error {
+	if !user.can(permWrite) {
+		return errForbidden
+	}
+
+	if err := db.writeEvent(newEventScheduleDelete(id), user); err != nil {
+		return fmt.Errorf("writing schedule delete event: %w", err)
+	}
+	return nil
+}
+
+// fireSchedule executes all transitions, that are due at the given time.
+func (db *Database) fireSchedule(now time.Time) error {
+	for _, s := range db.Schedule() {
+		if s.At.After(now) {
+			break
+		}
+
+		event, err := newEventStatus(s.State)
+		if err != nil {
+			return fmt.Errorf("creating state event for schedule %d: %w", s.ID, err)
+		}
+		event.Schedule = s.ID
+
+		if err := db.writeEvent(event, schedulerUser); err != nil {
+			return fmt.Errorf("writing state event for schedule %d: %w", s.ID, err)
+		}
+		log.Printf("Scheduled transition %d: Set state to %s", s.ID, s.State)
+	}
+	return nil
+}
+
+// runScheduler fires the due transitions until the context is done.
+func runScheduler(ctx context.Context, db *Database) {
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := db.fireSchedule(time.Now()); err != nil {
+			log.Printf("Error: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFireSchedule(t *testing.T) {
+	db, err := NewDB(NewMemoryStore(
+		`{"type":"schedule","payload":{"id":1,"state":3,"at":"2026-03-01T18:00:00+01:00"}}`,
+		`{"type":"schedule","payload":{"id":2,"state":1,"at":"2026-03-08T20:00:00+01:00"}}`,
+	))
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+
+	next, ok := db.NextTransition()
+	if !ok || next.ID != 1 {
+		t.Fatalf("next transition is %v, expected id 1", next)
+	}
+
+	if err := db.fireSchedule(time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("fireSchedule: %v", err)
+	}
+
+	if got := db.State(); got != stateOffer {
+		t.Errorf("state is %s, expected %s", got, stateOffer)
+	}
+
+	if got := db.Schedule(); len(got) != 1 || got[0].ID != 2 {
+		t.Errorf("schedule is %v, expected only id 2", got)
+	}
+
+	entries, err := db.History(HistoryFilter{Types: []string{"state"}})
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(entries) != 1 || entries[0].User != schedulerUser.Name || entries[0].Role != schedulerUser.Role {
+		t.Errorf("state change has user %v, expected the scheduler", entries)
+	}
+}
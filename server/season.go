@@ -0,0 +1,89 @@
+package server
+
+import (
+	"fmt"
+	"time"
+)
+
+// SeasonConfig is the period, the offers are made for.
+type SeasonConfig struct {
+	// Start and End are the first and the last month of the season.
+	Start Month `toml:"start"`
+	End   Month `toml:"end"`
+
+	// Months is the number of monthly payments. Defaults to the number of
+	// months from Start to End.
+	Months int `toml:"months"`
+
+	// ReferencePrefix is the prefix of the SEPA mandate reference. The
+	// reference is the prefix followed by the bieter id.
+	ReferencePrefix string `toml:"reference_prefix"`
+}
+
+func (s SeasonConfig) validate() error {
+	if s.Start.IsZero() || s.End.IsZero() {
+		return fmt.Errorf("start and end of the season have to be set")
+	}
+
+	length := monthsBetween(s.Start.Time, s.End.Time) + 1
+	if length < 1 {
+		return fmt.Errorf("season ends before it starts")
+	}
+
+	if s.Months < 0 || s.Months > length {
+		return fmt.Errorf("season has %d months, but %d payments are configured", length, s.Months)
+	}
+	return nil
+}
+
+// payments returns the number of monthly payments.
+func (s SeasonConfig) payments() int {
+	if s.Months == 0 {
+		return monthsBetween(s.Start.Time, s.End.Time) + 1
+	}
+	return s.Months
+}
+
+// paymentIndex returns the index of the payment in the month of date. Returns
+// false, if there is no payment in this month.
+func (s SeasonConfig) paymentIndex(date time.Time) (int, bool) {
+	index := monthsBetween(s.Start.Time, date)
+	return index, index >= 0 && index < s.payments()
+}
+
+// mandateReference returns the SEPA mandate reference of a bieter.
+func (s SeasonConfig) mandateReference(bieterID string) string {
+	return s.ReferencePrefix + bieterID
+}
+
+// monthsBetween returns the number of months from the month of a to the month
+// of b.
+func monthsBetween(a, b time.Time) int {
+	return (b.Year()-a.Year())*12 + int(b.Month()) - int(a.Month())
+}
+
+// Month is the first day of a month. In the config, it is written like
+// 2022-04.
+type Month struct {
+	time.Time
+}
+
+// NewMonth returns the given month.
+func NewMonth(year int, month time.Month) Month {
+	return Month{time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+// MarshalText formats the month like 2022-04.
+func (m Month) MarshalText() ([]byte, error) {
+	return []byte(m.Format("2006-01")), nil
+}
+
+// UnmarshalText parses a month like 2022-04.
+func (m *Month) UnmarshalText(text []byte) error {
+	t, err := time.Parse("2006-01", string(text))
+	if err != nil {
+		return fmt.Errorf("invalid month %q, expected format YYYY-MM", text)
+	}
+	m.Time = t
+	return nil
+}
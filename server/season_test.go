@@ -0,0 +1,64 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+func TestSeasonConfig(t *testing.T) {
+	var c struct {
+		Season SeasonConfig `toml:"season"`
+	}
+	content := `
+[season]
+start = "2024-01"
+end = "2024-10"
+months = 9
+reference_prefix = "24-"
+`
+	if err := toml.Unmarshal([]byte(content), &c); err != nil {
+		t.Fatalf("decoding season: %v", err)
+	}
+	s := c.Season
+
+	if err := s.validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	if got := s.payments(); got != 9 {
+		t.Errorf("season has %d payments, expected 9", got)
+	}
+
+	if got, ok := s.paymentIndex(time.Date(2024, time.September, 15, 0, 0, 0, 0, time.UTC)); !ok || got != 8 {
+		t.Errorf("payment index in september is %d %t, expected 8 true", got, ok)
+	}
+
+	if _, ok := s.paymentIndex(time.Date(2024, time.October, 1, 0, 0, 0, 0, time.UTC)); ok {
+		t.Errorf("got a payment in october")
+	}
+
+	if got := s.mandateReference("7"); got != "24-7" {
+		t.Errorf("mandate reference is %q, expected 24-7", got)
+	}
+
+	s.Months = 11
+	if err := s.validate(); err == nil {
+		t.Errorf("got no error for more payments than months")
+	}
+
+	s.Months = 0
+	s.End = NewMonth(2023, time.December)
+	if err := s.validate(); err == nil {
+		t.Errorf("got no error for a season that ends before it starts")
+	}
+}
+
+func TestMonthsBetween(t *testing.T) {
+	a := time.Date(2022, time.April, 1, 0, 0, 0, 0, time.UTC)
+	b := time.Date(2023, time.March, 31, 0, 0, 0, 0, time.UTC)
+	if got := monthsBetween(a, b); got != 11 {
+		t.Errorf("got %d, expected 11", got)
+	}
+}
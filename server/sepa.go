@@ -0,0 +1,343 @@
+package server
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SEPA sequence types.
+const (
+	sepaFirst     = "FRST"
+	sepaRecurring = "RCUR"
+	sepaOneOff    = "OOFF"
+)
+
+// sepaNamespaces are the supported versions of pain.008.
+var sepaNamespaces = map[string]string{
+	"02": "urn:iso:std:iso:20022:tech:xsd:pain.008.001.02",
+	"08": "urn:iso:std:iso:20022:tech:xsd:pain.008.001.08",
+}
+
+// sepaTransaction is one direct debit.
+type sepaTransaction struct {
+	BieterID string
+	Name     string
+	IBAN     string
+	BIC      string
+
+	// Amount in cent.
+	Amount   int
+	Sequence string
+}
+
+// sepaTransactions returns the direct debits for a collection date.
+//
+// Monthly payers are collected in each month of the season. Yearly payers
+// only in the first month. Bieters without an offer are skipped. If the data
+// of a bieter with an offer is invalid, an error is returned.
+func (db *Database) sepaTransactions(season SeasonConfig, date time.Time) ([]sepaTransaction, error) {
+	month, ok := season.paymentIndex(date)
+	if !ok {
+		return nil, validationError{fmt.Sprintf("Das Datum %s liegt nicht in der Saison", date.Format("02.01.2006"))}
+	}
+
+	db.RLock()
+	defer db.RUnlock()
+
+	var transactions []sepaTransaction
+	var errs fieldErrors
+	for id, payload := range db.bieter {
+		offer := db.offer[id]
+		if offer <= 0 {
+			continue
+		}
+
+		var data bieterData
+		if err := json.Unmarshal(payload, &data); err != nil {
+			errs = append(errs, fieldError{id, "Ungültige Daten"})
+			continue
+		}
+
+		if err := validateIBAN(data.IBAN); err != nil {
+			errs = append(errs, fieldError{id, fmt.Sprintf("%s: %v", data.Name, err)})
+			continue
+		}
+
+		t := sepaTransaction{
+			BieterID: id,
+			Name:     data.Kontoinhaber,
+			IBAN:     normalizeIBAN(data.IBAN),
+			Amount:   offer,
+			Sequence: sepaRecurring,
+		}
+
+		if t.Name == "" {
+			t.Name = data.Name
+		}
+
+		t.BIC, _ = bicForIBAN(t.IBAN)
+
+		switch {
+		case data.Abbuchung == abbuchungYearly && month != 0:
+			continue
+
+		case data.Abbuchung == abbuchungYearly:
+			t.Amount = offer * season.payments()
+			t.Sequence = sepaOneOff
+
+		case month == 0:
+			t.Sequence = sepaFirst
+		}
+
+		transactions = append(transactions, t)
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	sort.Slice(transactions, func(i, j int) bool {
+		return transactions[i].BieterID < transactions[j].BieterID
+	})
+	return transactions, nil
+}
+
+// SEPAExport creates a pain.008 xml file for all offers that are collected at
+// the given date.
+//
+// version is "02" or "08".
+func (db *Database) SEPAExport(config Config, version string, date time.Time) ([]byte, error) {
+	c := config.SEPA
+
+	if _, ok := sepaNamespaces[version]; !ok {
+		return nil, validationError{fmt.Sprintf("Unbekannte pain.008 Version %q", version)}
+	}
+
+	if err := validateIBAN(c.CreditorIBAN); err != nil {
+		return nil, fmt.Errorf("creditor iban in config: %w", err)
+	}
+
+	if c.CreditorID == "" {
+		return nil, fmt.Errorf("creditor id in config is empty")
+	}
+
+	if c.MandateDate != "" {
+		if _, err := time.Parse("2006-01-02", c.MandateDate); err != nil {
+			return nil, fmt.Errorf("mandate date in config: %w", err)
+		}
+	}
+
+	transactions, err := db.sepaTransactions(config.Season, date)
+	if err != nil {
+		return nil, fmt.Errorf("collecting transactions: %w", err)
+	}
+
+	doc := newSEPADocument(c, config.Season, version, date, time.Now(), transactions)
+	bs, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding xml: %w", err)
+	}
+
+	return append([]byte(xml.Header), bs...), nil
+}
+
+func newSEPADocument(c SEPAConfig, season SeasonConfig, version string, date time.Time, created time.Time, transactions []sepaTransaction) sepaDocument {
+	msgID := "BIETERRUNDE-" + created.Format("20060102150405")
+
+	mandateDate := c.MandateDate
+	if mandateDate == "" {
+		mandateDate = season.Start.Format("2006-01-02")
+	}
+
+	bySequence := make(map[string][]sepaTransaction)
+	for _, t := range transactions {
+		bySequence[t.Sequence] = append(bySequence[t.Sequence], t)
+	}
+
+	doc := sepaDocument{
+		Xmlns: sepaNamespaces[version],
+		Initiation: sepaInitiation{
+			GroupHeader: sepaGroupHeader{
+				MsgID:          msgID,
+				CreationTime:   created.Format("2006-01-02T15:04:05"),
+				NumberOfTx:     len(transactions),
+				ControlSum:     sepaAmount(sumAmounts(transactions)),
+				InitiatingName: sepaText(c.CreditorName, 70),
+			},
+		},
+	}
+
+	for _, seq := range []string{sepaFirst, sepaRecurring, sepaOneOff} {
+		group := bySequence[seq]
+		if len(group) == 0 {
+			continue
+		}
+
+		info := sepaPaymentInfo{
+			ID:            msgID + "-" + seq,
+			Method:        "DD",
+			NumberOfTx:    len(group),
+			ControlSum:    sepaAmount(sumAmounts(group)),
+			ServiceLevel:  "SEPA",
+			LocalInstr:    "CORE",
+			Sequence:      seq,
+			Collection:    date.Format("2006-01-02"),
+			CreditorName:  sepaText(c.CreditorName, 70),
+			CreditorIBAN:  normalizeIBAN(c.CreditorIBAN),
+			CreditorAgent: newSEPAAgent(version, c.CreditorBIC),
+			ChargeBearer:  "SLEV",
+			CreditorSchemeID: sepaSchemeID{
+				ID:     c.CreditorID,
+				Scheme: "SEPA",
+			},
+		}
+
+		for _, t := range group {
+			remittance := fmt.Sprintf("Ernteanteil %s", date.Format("01/2006"))
+			if seq == sepaOneOff {
+				remittance = fmt.Sprintf("Ernteanteil Saison ab %s", season.Start.Format("01/2006"))
+			}
+
+			info.Transactions = append(info.Transactions, sepaTransactionInfo{
+				EndToEndID:   sepaText(fmt.Sprintf("%s-%s", season.mandateReference(t.BieterID), date.Format("200601")), 35),
+				Amount:       sepaCurrencyAmount{Currency: "EUR", Value: sepaAmount(t.Amount)},
+				MandateID:    season.mandateReference(t.BieterID),
+				MandateDate:  mandateDate,
+				DebtorAgent:  newSEPAAgent(version, t.BIC),
+				DebtorName:   sepaText(t.Name, 70),
+				DebtorIBAN:   t.IBAN,
+				Unstructured: sepaText(remittance, 140),
+			})
+		}
+
+		doc.Initiation.PaymentInfos = append(doc.Initiation.PaymentInfos, info)
+	}
+
+	return doc
+}
+
+func sumAmounts(transactions []sepaTransaction) int {
+	var sum int
+	for _, t := range transactions {
+		sum += t.Amount
+	}
+	return sum
+}
+
+// sepaAmount formats an amount in cent like 12.34.
+func sepaAmount(cent int) string {
+	return fmt.Sprintf("%d.%02d", cent/100, cent%100)
+}
+
+// sepaText converts the text to the character set allowed in SEPA files and
+// truncates it to max characters.
+func sepaText(s string, max int) string {
+	replacer := strings.NewReplacer(
+		"ä", "ae", "ö", "oe", "ü", "ue",
+		"Ä", "Ae", "Ö", "Oe", "Ü", "Ue",
+		"ß", "ss", "&", "+",
+	)
+	s = replacer.Replace(s)
+
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case strings.ContainsRune("/-?:().,'+ ", r):
+			b.WriteRune(r)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+
+	s = strings.Join(strings.Fields(b.String()), " ")
+	if len(s) > max {
+		s = s[:max]
+	}
+	return s
+}
+
+type sepaDocument struct {
+	XMLName    xml.Name       `xml:"Document"`
+	Xmlns      string         `xml:"xmlns,attr"`
+	Initiation sepaInitiation `xml:"CstmrDrctDbtInitn"`
+}
+
+type sepaInitiation struct {
+	GroupHeader  sepaGroupHeader   `xml:"GrpHdr"`
+	PaymentInfos []sepaPaymentInfo `xml:"PmtInf"`
+}
+
+type sepaGroupHeader struct {
+	MsgID          string `xml:"MsgId"`
+	CreationTime   string `xml:"CreDtTm"`
+	NumberOfTx     int    `xml:"NbOfTxs"`
+	ControlSum     string `xml:"CtrlSum"`
+	InitiatingName string `xml:"InitgPty>Nm"`
+}
+
+type sepaPaymentInfo struct {
+	ID               string                `xml:"PmtInfId"`
+	Method           string                `xml:"PmtMtd"`
+	NumberOfTx       int                   `xml:"NbOfTxs"`
+	ControlSum       string                `xml:"CtrlSum"`
+	ServiceLevel     string                `xml:"PmtTpInf>SvcLvl>Cd"`
+	LocalInstr       string                `xml:"PmtTpInf>LclInstrm>Cd"`
+	Sequence         string                `xml:"PmtTpInf>SeqTp"`
+	Collection       string                `xml:"ReqdColltnDt"`
+	CreditorName     string                `xml:"Cdtr>Nm"`
+	CreditorIBAN     string                `xml:"CdtrAcct>Id>IBAN"`
+	CreditorAgent    sepaAgent             `xml:"CdtrAgt"`
+	ChargeBearer     string                `xml:"ChrgBr"`
+	CreditorSchemeID sepaSchemeID          `xml:"CdtrSchmeId>Id>PrvtId>Othr"`
+	Transactions     []sepaTransactionInfo `xml:"DrctDbtTxInf"`
+}
+
+type sepaSchemeID struct {
+	ID     string `xml:"Id"`
+	Scheme string `xml:"SchmeNm>Prtry"`
+}
+
+type sepaTransactionInfo struct {
+	EndToEndID   string             `xml:"PmtId>EndToEndId"`
+	Amount       sepaCurrencyAmount `xml:"InstdAmt"`
+	MandateID    string             `xml:"DrctDbtTx>MndtRltdInf>MndtId"`
+	MandateDate  string             `xml:"DrctDbtTx>MndtRltdInf>DtOfSgntr"`
+	DebtorAgent  sepaAgent          `xml:"DbtrAgt"`
+	DebtorName   string             `xml:"Dbtr>Nm"`
+	DebtorIBAN   string             `xml:"DbtrAcct>Id>IBAN"`
+	Unstructured string             `xml:"RmtInf>Ustrd"`
+}
+
+type sepaCurrencyAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+// sepaAgent is the bank of the creditor or debtor. Version 02 uses the element
+// BIC, version 08 BICFI. Without a BIC, NOTPROVIDED is used.
+type sepaAgent struct {
+	BIC   string          `xml:"FinInstnId>BIC,omitempty"`
+	BICFI string          `xml:"FinInstnId>BICFI,omitempty"`
+	Other *sepaAgentOther `xml:"FinInstnId>Othr,omitempty"`
+}
+
+type sepaAgentOther struct {
+	ID string `xml:"Id"`
+}
+
+func newSEPAAgent(version string, bic string) sepaAgent {
+	switch {
+	case bic == "":
+		return sepaAgent{Other: &sepaAgentOther{ID: "NOTPROVIDED"}}
+	case version == "02":
+		return sepaAgent{BIC: bic}
+	default:
+		return sepaAgent{BICFI: bic}
+	}
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"encoding/xml"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sepaTestEvents = `
+{"type":"update","payload":{"id":"1","payload":{"name":"Hugo Müller","iban":"DE89 3704 0044 0532 0130 00","abbuchung":0}}}
+{"type":"update","payload":{"id":"2","payload":{"name":"Erik","kontoinhaber":"Erika","iban":"DE02120300000000202051","abbuchung":1}}}
+{"type":"update","payload":{"id":"3","payload":{"name":"Ohne Gebot","iban":"DE02120300000000202051"}}}
+{"type":"offer","payload":{"id":"1","offer":5000}}
+{"type":"offer","payload":{"id":"2","offer":6050}}
+`
+
+func sepaTestDB(t *testing.T) *Database {
+	t.Helper()
+
+	db, err := loadDatabase(strings.NewReader(sepaTestEvents), ReplayLimit{})
+	if err != nil {
+		t.Fatalf("loadDatabase: %v", err)
+	}
+	return db
+}
+
+func TestSEPATransactions(t *testing.T) {
+	db := sepaTestDB(t)
+	season := DefaultConfig().Season
+
+	first, err := db.sepaTransactions(season, season.Start.Time)
+	if err != nil {
+		t.Fatalf("sepaTransactions: %v", err)
+	}
+
+	expect := []sepaTransaction{
+		{BieterID: "1", Name: "Hugo Müller", IBAN: "DE89370400440532013000", BIC: "COBADEFFXXX", Amount: 5000, Sequence: sepaFirst},
+		{BieterID: "2", Name: "Erika", IBAN: "DE02120300000000202051", BIC: "BYLADEM1001", Amount: 6050 * 12, Sequence: sepaOneOff},
+	}
+	if len(first) != len(expect) {
+		t.Fatalf("got %v, expected %v", first, expect)
+	}
+	for i := range expect {
+		if first[i] != expect[i] {
+			t.Errorf("transaction %d is %v, expected %v", i, first[i], expect[i])
+		}
+	}
+
+	later, err := db.sepaTransactions(season, season.Start.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatalf("sepaTransactions: %v", err)
+	}
+	if len(later) != 1 || later[0].Sequence != sepaRecurring {
+		t.Errorf("second month has transactions %v, expected one RCUR", later)
+	}
+
+	if _, err := db.sepaTransactions(season, season.Start.AddDate(0, 12, 0)); err == nil {
+		t.Errorf("got no error for a date after the season")
+	}
+}
+
+func TestSEPAExport(t *testing.T) {
+	db := sepaTestDB(t)
+	c := DefaultConfig()
+	c.SEPA.CreditorIBAN = "DE02120300000000202051"
+	c.SEPA.CreditorBIC = "BYLADEM1001"
+
+	for _, version := range []string{"02", "08"} {
+		t.Run(version, func(t *testing.T) {
+			bs, err := db.SEPAExport(c, version, c.Season.Start.Time)
+			if err != nil {
+				t.Fatalf("SEPAExport: %v", err)
+			}
+
+			var doc sepaDocument
+			if err := xml.Unmarshal(bs, &doc); err != nil {
+				t.Fatalf("decoding export: %v", err)
+			}
+
+			header := doc.Initiation.GroupHeader
+			if header.NumberOfTx != 2 || header.ControlSum != "776.00" {
+				t.Errorf("header has %d transactions with sum %s, expected 2 and 776.00", header.NumberOfTx, header.ControlSum)
+			}
+
+			if len(doc.Initiation.PaymentInfos) != 2 {
+				t.Fatalf("got %d payment infos, expected 2", len(doc.Initiation.PaymentInfos))
+			}
+
+			tx := doc.Initiation.PaymentInfos[0].Transactions[0]
+			if tx.MandateID != "221" || tx.DebtorName != "Hugo Mueller" {
+				t.Errorf("got mandate %q for %q, expected 221 for Hugo Mueller", tx.MandateID, tx.DebtorName)
+			}
+
+			validateSchema(t, bs, version)
+		})
+	}
+}
+
+// validateSchema validates the export with xmllint against the official
+// schema in testdata.
+//
+// The schemas are not part of the code. They can be downloaded from the
+// message archive of ISO 20022:
+// https://www.iso20022.org/catalogue-messages/iso-20022-messages-archive
+func validateSchema(t *testing.T, content []byte, version string) {
+	t.Helper()
+
+	schema := filepath.Join("testdata", "pain.008.001."+version+".xsd")
+	if _, err := os.Stat(schema); err != nil {
+		t.Fatalf("schema for pain.008.001.%s is missing. Download it from the message archive of ISO 20022 to %s: %v", version, schema, err)
+	}
+
+	xmllint, err := exec.LookPath("xmllint")
+	if err != nil {
+		t.Fatalf("xmllint is needed to validate the export: %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "sepa.xml")
+	if err := os.WriteFile(file, content, 0600); err != nil {
+		t.Fatalf("writing export: %v", err)
+	}
+
+	out, err := exec.Command(xmllint, "--noout", "--schema", schema, file).CombinedOutput()
+	if err != nil {
+		t.Errorf("export does not match pain.008.001.%s: %v\n%s", version, err, out)
+	}
+}
+
+func TestSEPAText(t *testing.T) {
+	got := sepaText("Jörg & Söhne <Gärtnerei>", 70)
+	if got != "Joerg + Soehne Gaertnerei" {
+		t.Errorf("got %q", got)
+	}
+}
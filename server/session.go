@@ -0,0 +1,252 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// sessionCookie is the name of the cookie with the session token.
+const sessionCookie = "session"
+
+type contextKey int
+
+const (
+	// userKey is the context key for the user of a request with a valid
+	// session.
+	userKey contextKey = iota
+
+	// requestKey is the context key for the requestInfo.
+	requestKey
+)
+
+// HashPassword returns the bcrypt hash of a password for the config.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("hashing password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// dummyHash is compared for unknown users, so the response time does not
+// tell, if a user exists.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy"), bcrypt.DefaultCost)
+
+// authenticate returns the user with the name and password.
+//
+// An empty name or "admin" is the admin of admin_password_hash or
+// admin_password, if there is no user with this name.
+func (c Config) authenticate(name, password string) (User, bool) {
+	for _, u := range c.Users {
+		if u.Name != name {
+			continue
+		}
+
+		if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) != nil {
+			return User{}, false
+		}
+		return User{Name: u.Name, Role: u.Role, Verteilstelle: u.Verteilstelle}, true
+	}
+
+	if name == "" || name == "admin" {
+		if c.checkPassword(password) {
+			return User{Name: "admin", Role: roleBoard}, true
+		}
+		return User{}, false
+	}
+
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+	return User{}, false
+}
+
+// checkPassword returns true, if password is the admin password.
+//
+// The plaintext admin_password is only used, if there is no hash.
+func (c Config) checkPassword(password string) bool {
+	if c.AdminPWHash != "" {
+		return bcrypt.CompareHashAndPassword([]byte(c.AdminPWHash), []byte(password)) == nil
+	}
+
+	if c.AdminPW == "" {
+		return false
+	}
+
+	// Compare the hashes, so the time does not depend on the password or
+	// its length.
+	got := sha256.Sum256([]byte(password))
+	expected := sha256.Sum256([]byte(c.AdminPW))
+	return subtle.ConstantTimeCompare(got[:], expected[:]) == 1
+}
+
+type session struct {
+	user    User
+	expires time.Time
+}
+
+// sessionStore holds the admin sessions in memory.
+//
+// A session token has the form id.expires.signature. The signature is a hmac
+// with a random key, so all sessions end when the server restarts. A session
+// is only valid, as long as its id is in the store.
+type sessionStore struct {
+	mu       sync.Mutex
+	secret   []byte
+	duration time.Duration
+	sessions map[string]session
+
+	// now can be replaced in tests.
+	now func() time.Time
+}
+
+func newSessionStore(duration time.Duration) (*sessionStore, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("creating session secret: %w", err)
+	}
+
+	return &sessionStore{
+		secret:   secret,
+		duration: duration,
+		sessions: make(map[string]session),
+		now:      time.Now,
+	}, nil
+}
+
+// create starts a new session for the user and returns its token.
+func (s *sessionStore) create(user User) (string, time.Time, error) {
+	id, err := newToken()
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("creating session id: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for sid, session := range s.sessions {
+		if now.After(session.expires) {
+			delete(s.sessions, sid)
+		}
+	}
+
+	expires := now.Add(s.duration)
+	s.sessions[id] = session{user: user, expires: expires}
+
+	payload := id + "." + strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + s.sign(payload), expires, nil
+}
+
+// user returns the user of the session, if the token belongs to an active
+// session.
+func (s *sessionStore) user(token string) (User, bool) {
+	id, ok := s.verify(token)
+	if !ok {
+		return User{}, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok || !s.now().Before(session.expires) {
+		return User{}, false
+	}
+	return session.user, true
+}
+
+// revoke ends the session of the token.
+func (s *sessionStore) revoke(token string) {
+	id, ok := s.verify(token)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+}
+
+// revokeAll ends all sessions.
+func (s *sessionStore) revokeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = make(map[string]session)
+}
+
+// verify checks the signature and expiry of a token and returns its session
+// id.
+func (s *sessionStore) verify(token string) (string, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", false
+	}
+
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(s.sign(payload))) {
+		return "", false
+	}
+
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || s.now().Unix() >= expires {
+		return "", false
+	}
+	return parts[0], true
+}
+
+func (s *sessionStore) sign(payload string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// middleware adds the user of the session to the request context.
+func (s *sessionStore) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := sessionToken(r); token != "" {
+			if user, ok := s.user(token); ok {
+				r = r.WithContext(context.WithValue(r.Context(), userKey, user))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sessionToken returns the session token from the cookie or the Auth header.
+func sessionToken(r *http.Request) string {
+	if token := r.Header.Get("Auth"); token != "" {
+		return token
+	}
+
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// requestUser returns the user of a request with the ip and request id. The
+// user is the zero value, if there is no valid session.
+func requestUser(r *http.Request) User {
+	user, _ := r.Context().Value(userKey).(User)
+	info, _ := r.Context().Value(requestKey).(requestInfo)
+	user.IP = info.ip
+	user.RequestID = info.id
+	return user
+}
+
+// can returns true, if the user of the request has the permission.
+func can(r *http.Request, p Permission) bool {
+	return requestUser(r).can(p)
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func sessionValid(s *sessionStore, token string) bool {
+	_, ok := s.user(token)
+	return ok
+}
+
+func TestSessionStore(t *testing.T) {
+	s, err := newSessionStore(time.Hour)
+	if err != nil {
+		t.Fatalf("newSessionStore: %v", err)
+	}
+
+	token, _, err := s.create(systemUser)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	if !sessionValid(s, token) {
+		t.Errorf("new token is not valid")
+	}
+
+	parts := strings.Split(token, ".")
+	if sessionValid(s, parts[0]+".9999999999."+parts[2]) {
+		t.Errorf("token with a changed expiry is valid")
+	}
+
+	s.revoke(token)
+	if sessionValid(s, token) {
+		t.Errorf("revoked token is valid")
+	}
+
+	token, _, _ = s.create(systemUser)
+	s.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if sessionValid(s, token) {
+		t.Errorf("expired token is valid")
+	}
+}
+
+func TestLogin(t *testing.T) {
+	hash, err := HashPassword("secret")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+
+	config := DefaultConfig()
+	config.AdminPWHash = hash
+
+	sessions, err := newSessionStore(time.Hour)
+	if err != nil {
+		t.Fatalf("newSessionStore: %v", err)
+	}
+
+	router := mux.NewRouter()
+	router.Use(sessions.middleware)
+	handleLogin(router, config, sessions, newFailureLimiter(config.RateLimit))
+	router.Path("/api/admin").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !can(r, permWrite) {
+			w.WriteHeader(403)
+		}
+	})
+
+	request := func(method, path, body string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := request("POST", "/api/login", `{"password":"wrong"}`); w.Code != 401 {
+		t.Errorf("wrong password returned %d, expected 401", w.Code)
+	}
+
+	w := request("POST", "/api/login", `{"password":"secret"}`)
+	if w.Code != 200 {
+		t.Fatalf("login returned %d: %s", w.Code, w.Body)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != sessionCookie {
+		t.Fatalf("got cookies %v, expected the session cookie", cookies)
+	}
+
+	if w := request("GET", "/api/admin", "", cookies[0]); w.Code != 200 {
+		t.Errorf("admin request with session returned %d", w.Code)
+	}
+
+	request("POST", "/api/logout", "", cookies[0])
+
+	if w := request("GET", "/api/admin", "", cookies[0]); w.Code != 403 {
+		t.Errorf("admin request after logout returned %d, expected 403", w.Code)
+	}
+}
@@ -0,0 +1,217 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var errSnapshotMismatch = errors.New("snapshot does not match the database file")
+
+// snapshot is the state of the database after a number of events.
+type snapshot struct {
+	Events int                        `json:"events"`
+	Bieter map[string]json.RawMessage `json:"bieter"`
+	Tokens map[string]string          `json:"tokens"`
+	Offer  map[string]int             `json:"offer"`
+	State  ServiceState               `json:"state"`
+	Round  int                        `json:"round"`
+	Rounds []map[string]int           `json:"rounds"`
+	Budget Budget                     `json:"budget"`
+
+	Schedule       []ScheduledState `json:"schedule"`
+	LastScheduleID int              `json:"last_schedule_id"`
+
+	Verteilstellen      []Verteilstelle `json:"verteilstellen"`
+	LastVerteilstelleID int             `json:"last_verteilstelle_id"`
+}
+
+// loadSnapshot creates a database from an encoded snapshot.
+func loadSnapshot(bs []byte) (*Database, error) {
+	var s snapshot
+	if err := json.Unmarshal(bs, &s); err != nil {
+		return nil, fmt.Errorf("decoding snapshot: %w", err)
+	}
+
+	db := emptyDatabase()
+	if s.Bieter != nil {
+		db.bieter = s.Bieter
+	}
+	for id, token := range s.Tokens {
+		db.setToken(id, token)
+	}
+	if s.Offer != nil {
+		db.offer = s.Offer
+	}
+	db.state = s.State
+	if s.Round > 0 {
+		db.round = s.Round
+	}
+	db.rounds = s.Rounds
+	db.budget = s.Budget
+	for _, scheduled := range s.Schedule {
+		db.schedule[scheduled.ID] = scheduled
+	}
+	db.lastScheduleID = s.LastScheduleID
+	for _, v := range s.Verteilstellen {
+		db.verteilstellen[v.ID] = v
+	}
+	db.lastVerteilstelleID = s.LastVerteilstelleID
+	db.snapshotAt = s.Events
+	return db, nil
+}
+
+// writeSnapshot saves the current state, if the store supports snapshots.
+//
+// Has to be called with the write lock.
+func (db *Database) writeSnapshot() error {
+	snapshotter, ok := db.store.(Snapshotter)
+	if !ok {
+		return nil
+	}
+
+	s := snapshot{
+		Events: db.events,
+		Bieter: db.bieter,
+		Tokens: db.bieterToken,
+		Offer:  db.offer,
+		State:  db.state,
+		Round:  db.round,
+		Rounds: db.rounds,
+		Budget: db.budget,
+
+		Schedule:       db.sortedSchedule(),
+		LastScheduleID: db.lastScheduleID,
+
+		Verteilstellen:      db.sortedVerteilstellen(),
+		LastVerteilstelleID: db.lastVerteilstelleID,
+	}
+
+	bs, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("encoding snapshot: %w", err)
+	}
+
+	if err := snapshotter.SaveSnapshot(bs); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+
+	db.snapshotAt = db.events
+	return nil
+}
+
+// compactEvents returns the minimal list of events, that create the current
+// state of the database.
+func (db *Database) compactEvents() []Event {
+	bieterIDs := make([]string, 0, len(db.bieter))
+	for id := range db.bieter {
+		bieterIDs = append(bieterIDs, id)
+	}
+	sort.Strings(bieterIDs)
+
+	var events []Event
+	for _, v := range db.sortedVerteilstellen() {
+		events = append(events, eventVerteilstelle{Verteilstelle: v})
+	}
+
+	for _, id := range bieterIDs {
+		events = append(events, eventUpdate{ID: id, Payload: db.bieter[id], Token: db.bieterToken[id]})
+	}
+
+	for i, offers := range db.rounds {
+		events = append(events, offerEvents(offers)...)
+		events = append(events, eventRoundStart{Round: i + 2})
+	}
+
+	events = append(events, offerEvents(db.offer)...)
+
+	if db.budget != (Budget{}) {
+		events = append(events, eventBudget{Budget: db.budget})
+	}
+
+	if db.state != stateRegistration {
+		events = append(events, eventServiceState{NewState: db.state})
+	}
+
+	for _, scheduled := range db.sortedSchedule() {
+		events = append(events, eventSchedule{scheduled})
+	}
+
+	return append(events, db.counterEvents()...)
+}
+
+// counterEvents returns the event for the last schedule and verteilstelle ids,
+// so deleted ids are not used again.
+func (db *Database) counterEvents() []Event {
+	if db.lastScheduleID == 0 && db.lastVerteilstelleID == 0 {
+		return nil
+	}
+	return []Event{eventCounters{LastSchedule: db.lastScheduleID, LastVerteilstelle: db.lastVerteilstelleID}}
+}
+
+// offerEvents returns one offer event for each offer sorted by the bieter id.
+func offerEvents(offers map[string]int) []Event {
+	ids := make([]string, 0, len(offers))
+	for id := range offers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	events := make([]Event, len(ids))
+	for i, id := range ids {
+		events[i] = eventOffer{ID: id, Offer: offers[id]}
+	}
+	return events
+}
+
+// Compact replaces the events in the store with the minimal list of events.
+func (db *Database) Compact() (backup string, err error) {
+	db.Lock()
+	defer db.Unlock()
+
+	compacter, ok := db.store.(Compacter)
+	if !ok {
+		return "", fmt.Errorf("event store does not support compaction")
+	}
+
+	events := db.compactEvents()
+
+	records := make([][]byte, len(events))
+	for i, e := range events {
+		bs, err := encodeEvent(e, i+1, systemUser)
+		if err != nil {
+			return "", fmt.Errorf("encoding event: %w", err)
+		}
+		records[i] = bs
+	}
+
+	backup, err = compacter.Replace(records)
+	if err != nil {
+		return "", fmt.Errorf("replacing events: %w", err)
+	}
+
+	db.events = len(records)
+	db.snapshotAt = 0
+	return backup, nil
+}
+
+// Compact compacts the configured event store.
+//
+// It fails, if the database file is used by the running server.
+func Compact(configFile, dbFile string) error {
+	_, db, err := openConfigDB(configFile, dbFile)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	before := db.events
+	backup, err := db.Compact()
+	if err != nil {
+		return fmt.Errorf("compacting: %w", err)
+	}
+
+	fmt.Printf("Compacted database from %d to %d events. Backup: %s\n", before, db.events, backup)
+	return nil
+}
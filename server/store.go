@@ -0,0 +1,165 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// EventStore saves the encoded events of the database.
+type EventStore interface {
+	// Append adds one encoded event to the end of the store. The order of
+	// calls to Append is the order of the records.
+	//
+	// The returned function blocks until the record is durable. It returns
+	// an error, if the record could not be saved.
+	Append(record []byte) (wait func() error)
+
+	// Iterate calls fn for each record in the order they were appended.
+	Iterate(fn func(record []byte) error) error
+
+	// Close closes the store.
+	Close() error
+}
+
+// Snapshotter is an optional interface for an EventStore, that can save
+// snapshots of the database.
+type Snapshotter interface {
+	// LoadSnapshot returns the last saved snapshot or nil, if there is no
+	// snapshot.
+	LoadSnapshot() ([]byte, error)
+
+	// SaveSnapshot saves a snapshot.
+	SaveSnapshot(data []byte) error
+}
+
+// Compacter is an optional interface for an EventStore, that can replace all
+// its records.
+type Compacter interface {
+	// Replace replaces all records and removes the snapshot. If the store
+	// keeps a backup of the old records, its name is returned.
+	Replace(records [][]byte) (backup string, err error)
+}
+
+// OpenStore opens the event store configured in the config.
+//
+// dbFile is used, if the config does not specify a file.
+func OpenStore(c Config, dbFile string) (EventStore, error) {
+	if c.DBFile != "" {
+		dbFile = c.DBFile
+	}
+
+	switch c.DBBackend {
+	case "", "file":
+		return NewFileStore(dbFile)
+
+	case "sqlite":
+		if c.DBFile == "" {
+			dbFile = strings.TrimSuffix(dbFile, filepath.Ext(dbFile)) + ".sqlite"
+		}
+		return NewSQLiteStore(dbFile)
+
+	case "memory":
+		return NewMemoryStore(), nil
+
+	default:
+		return nil, fmt.Errorf("unknown db_backend %q", c.DBBackend)
+	}
+}
+
+// scanLines calls fn for each non empty line in r.
+func scanLines(r io.Reader, fn func(line []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 10*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("scanning events: %w", err)
+	}
+	return nil
+}
+
+// MemoryStore is an EventStore that only keeps the events in memory.
+//
+// It is meant for tests.
+type MemoryStore struct {
+	mu       sync.Mutex
+	records  [][]byte
+	snapshot []byte
+}
+
+// NewMemoryStore creates a MemoryStore that contains the given records.
+func NewMemoryStore(records ...string) *MemoryStore {
+	s := &MemoryStore{}
+	for _, r := range records {
+		s.records = append(s.records, []byte(r))
+	}
+	return s
+}
+
+// Append adds a record.
+func (s *MemoryStore) Append(record []byte) func() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records = append(s.records, append([]byte(nil), record...))
+	return func() error { return nil }
+}
+
+// Iterate calls fn for each record.
+func (s *MemoryStore) Iterate(fn func(record []byte) error) error {
+	s.mu.Lock()
+	records := s.records
+	s.mu.Unlock()
+
+	for _, r := range records {
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close does nothing.
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// LoadSnapshot returns the saved snapshot.
+func (s *MemoryStore) LoadSnapshot() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.snapshot, nil
+}
+
+// SaveSnapshot saves a snapshot.
+func (s *MemoryStore) SaveSnapshot(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.snapshot = append([]byte(nil), data...)
+	return nil
+}
+
+// Replace replaces all records. It does not keep a backup.
+func (s *MemoryStore) Replace(records [][]byte) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records = records
+	s.snapshot = nil
+	return "", nil
+}
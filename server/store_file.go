@@ -0,0 +1,319 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FileStore saves the events in a jsonl file. Each line is one event.
+//
+// The file is kept open for appending. Concurrent appends are written
+// directly but share one fsync (group commit).
+type FileStore struct {
+	file string
+
+	mu      sync.Mutex
+	f       *os.File
+	size    int64
+	written uint64
+	err     error
+
+	syncMu sync.Mutex
+	synced uint64
+}
+
+// NewFileStore opens or creates the database file.
+//
+// The file is locked as long as the store is open, so only one process can use
+// it. If the last line of the file was not written completely, for example
+// after a crash, it is removed.
+func NewFileStore(file string) (*FileStore, error) {
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("open db file: %w", err)
+	}
+
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if err := repairTornLine(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("repairing last line: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("stat db file: %w", err)
+	}
+
+	return &FileStore{file: file, f: f, size: info.Size()}, nil
+}
+
+// errDBLocked is returned, if the database file is used by another process.
+var errDBLocked = errors.New("database file is used by another process. Is the server running?")
+
+// lockFile takes an exclusive lock on the file. It fails at once, if another
+// process holds the lock.
+func lockFile(f *os.File) error {
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return fmt.Errorf("%s: %w", f.Name(), errDBLocked)
+		}
+		return fmt.Errorf("lock db file: %w", err)
+	}
+	return nil
+}
+
+// repairTornLine makes sure, that the file ends with a newline.
+//
+// If the last line is valid json, only the newline is missing. In other case
+// the line is a partial write and is truncated.
+func repairTornLine(f *os.File) error {
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat: %w", err)
+	}
+	size := info.Size()
+	if size == 0 {
+		return nil
+	}
+
+	// Search backwards for the last newline.
+	const chunkSize = 4096
+	lineStart := int64(0)
+	buf := make([]byte, chunkSize)
+	for end := size; end > 0; end -= chunkSize {
+		start := end - chunkSize
+		if start < 0 {
+			start = 0
+		}
+
+		n, err := f.ReadAt(buf[:end-start], start)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("reading file: %w", err)
+		}
+
+		if end == size && n > 0 && buf[n-1] == '\n' {
+			return nil
+		}
+
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+			lineStart = start + int64(i) + 1
+			break
+		}
+	}
+
+	tail := make([]byte, size-lineStart)
+	if _, err := f.ReadAt(tail, lineStart); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("reading last line: %w", err)
+	}
+
+	if json.Valid(bytes.TrimSpace(tail)) {
+		if _, err := f.Write([]byte{'\n'}); err != nil {
+			return fmt.Errorf("adding newline: %w", err)
+		}
+		return f.Sync()
+	}
+
+	log.Printf("Warning: Removing incomplete last line from database file: %q", tail)
+	if err := f.Truncate(lineStart); err != nil {
+		return fmt.Errorf("truncate file: %w", err)
+	}
+	return f.Sync()
+}
+
+// Append writes the record as a new line to the file. The returned function
+// blocks until the line is synced to disk.
+func (s *FileStore) Append(record []byte) func() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		err := s.err
+		return func() error { return err }
+	}
+
+	line := append(append([]byte(nil), record...), '\n')
+	if _, err := s.f.Write(line); err != nil {
+		// The file could contain a partial line. No more events can be
+		// written until the file is repaired on the next start.
+		s.err = fmt.Errorf("writing event to file: %q: %w", record, err)
+		err := s.err
+		return func() error { return err }
+	}
+
+	s.size += int64(len(line))
+	s.written++
+	n := s.written
+	return func() error { return s.syncUntil(n) }
+}
+
+// syncUntil makes sure, that the first n records are synced to disk.
+//
+// If another goroutine is syncing, it waits for it. Its sync probably
+// includes record n, so nothing has to be done.
+func (s *FileStore) syncUntil(n uint64) error {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	if s.synced >= n {
+		return nil
+	}
+
+	s.mu.Lock()
+	target := s.written
+	f := s.f
+	s.mu.Unlock()
+
+	if err := f.Sync(); err != nil {
+		err = fmt.Errorf("sync database file: %w", err)
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+		return err
+	}
+
+	s.synced = target
+	return nil
+}
+
+// Iterate calls fn for each line in the file.
+//
+// Only complete lines are read, so it is safe to call Iterate while events are
+// appended.
+func (s *FileStore) Iterate(fn func(record []byte) error) error {
+	s.mu.Lock()
+	size := s.size
+	s.mu.Unlock()
+
+	f, err := os.Open(s.file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("open database file: %w", err)
+	}
+	defer f.Close()
+
+	return scanLines(io.LimitReader(f, size), fn)
+}
+
+// Close closes the file.
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.f.Close()
+}
+
+func (s *FileStore) snapshotFile() string {
+	return s.file + ".snapshot"
+}
+
+// LoadSnapshot reads the snapshot next to the database file.
+func (s *FileStore) LoadSnapshot() ([]byte, error) {
+	bs, err := os.ReadFile(s.snapshotFile())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading snapshot file: %w", err)
+	}
+	return bs, nil
+}
+
+// SaveSnapshot writes the snapshot next to the database file.
+func (s *FileStore) SaveSnapshot(data []byte) error {
+	return writeFileAtomic(s.snapshotFile(), data)
+}
+
+// Replace rewrites the database file. The old file is kept as a backup.
+func (s *FileStore) Replace(records [][]byte) (string, error) {
+	var content []byte
+	for _, r := range records {
+		content = append(content, r...)
+		content = append(content, '\n')
+	}
+
+	backup := fmt.Sprintf("%s.%s.bak", s.file, time.Now().Format("20060102-150405"))
+	if err := os.Link(s.file, backup); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("creating backup: %w", err)
+		}
+		backup = ""
+	}
+
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := writeFileAtomic(s.file, content); err != nil {
+		return "", fmt.Errorf("writing compacted database: %w", err)
+	}
+
+	f, err := os.OpenFile(s.file, os.O_APPEND|os.O_RDWR, 0600)
+	if err != nil {
+		return "", fmt.Errorf("reopen db file: %w", err)
+	}
+
+	// The lock belongs to the old file, so the new one has to be locked.
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return "", err
+	}
+	s.f.Close()
+	s.f = f
+	s.size = int64(len(content))
+
+	if err := os.Remove(s.snapshotFile()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("removing old snapshot: %w", err)
+	}
+
+	return backup, nil
+}
+
+// writeFileAtomic writes the content to a temporary file and moves it to the
+// given name.
+func writeFileAtomic(file string, content []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing temp file: %w", err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("sync temp file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing temp file: %w", err)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return fmt.Errorf("set file mode: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return fmt.Errorf("rename temp file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	// Registers the pure go sqlite driver.
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS events (
+	id     INTEGER PRIMARY KEY AUTOINCREMENT,
+	record TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS snapshot (
+	id   INTEGER PRIMARY KEY CHECK (id = 1),
+	data BLOB NOT NULL
+);
+`
+
+// SQLiteStore saves the events in a sqlite database.
+type SQLiteStore struct {
+	file string
+	db   *sql.DB
+}
+
+// NewSQLiteStore opens or creates a sqlite database.
+func NewSQLiteStore(file string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", file+"?_pragma=journal_mode(WAL)&_pragma=synchronous(FULL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite database: %w", err)
+	}
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating schema: %w", err)
+	}
+
+	return &SQLiteStore{file: file, db: db}, nil
+}
+
+// Append inserts the record. Each insert is its own transaction, so the
+// record is durable when Append returns.
+func (s *SQLiteStore) Append(record []byte) func() error {
+	_, err := s.db.Exec(`INSERT INTO events (record) VALUES (?)`, string(record))
+	return func() error {
+		if err != nil {
+			return fmt.Errorf("inserting event: %w", err)
+		}
+		return nil
+	}
+}
+
+// Iterate calls fn for each record.
+func (s *SQLiteStore) Iterate(fn func(record []byte) error) error {
+	rows, err := s.db.Query(`SELECT record FROM events ORDER BY id`)
+	if err != nil {
+		return fmt.Errorf("query events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var record []byte
+		if err := rows.Scan(&record); err != nil {
+			return fmt.Errorf("scanning event: %w", err)
+		}
+
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Close closes the database.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// LoadSnapshot returns the saved snapshot.
+func (s *SQLiteStore) LoadSnapshot() ([]byte, error) {
+	var data []byte
+	err := s.db.QueryRow(`SELECT data FROM snapshot WHERE id = 1`).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+	return data, nil
+}
+
+// SaveSnapshot saves the snapshot.
+func (s *SQLiteStore) SaveSnapshot(data []byte) error {
+	_, err := s.db.Exec(`INSERT INTO snapshot (id, data) VALUES (1, ?) ON CONFLICT (id) DO UPDATE SET data = excluded.data`, data)
+	if err != nil {
+		return fmt.Errorf("saving snapshot: %w", err)
+	}
+	return nil
+}
+
+// Replace replaces all records in one transaction. A copy of the old database
+// is kept as backup.
+func (s *SQLiteStore) Replace(records [][]byte) (backup string, err error) {
+	backup = fmt.Sprintf("%s.%s.bak", s.file, time.Now().Format("20060102-150405"))
+	if _, err := s.db.Exec(`VACUUM INTO ?`, backup); err != nil {
+		return "", fmt.Errorf("creating backup: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	for _, stmt := range []string{`DELETE FROM events`, `DELETE FROM snapshot`, `DELETE FROM sqlite_sequence WHERE name = 'events'`} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return "", fmt.Errorf("clearing database: %w", err)
+		}
+	}
+
+	for _, r := range records {
+		if _, err := tx.Exec(`INSERT INTO events (record) VALUES (?)`, string(r)); err != nil {
+			return "", fmt.Errorf("inserting event: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("commit: %w", err)
+	}
+
+	return backup, nil
+}
@@ -0,0 +1,277 @@
+package server
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestStores(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		open func(t *testing.T, dir string) EventStore
+	}{
+		{
+			"memory",
+			func(t *testing.T, dir string) EventStore {
+				return NewMemoryStore()
+			},
+		},
+		{
+			"file",
+			func(t *testing.T, dir string) EventStore {
+				s, err := NewFileStore(filepath.Join(dir, "db.jsonl"))
+				if err != nil {
+					t.Fatalf("NewFileStore: %v", err)
+				}
+				return s
+			},
+		},
+		{
+			"sqlite",
+			func(t *testing.T, dir string) EventStore {
+				s, err := NewSQLiteStore(filepath.Join(dir, "db.sqlite"))
+				if err != nil {
+					t.Fatalf("NewSQLiteStore: %v", err)
+				}
+				return s
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			store := tt.open(t, dir)
+
+			// The memory store can not be reopened.
+			reopen := func() *Database {
+				if _, ok := store.(*MemoryStore); !ok {
+					store.Close()
+					store = tt.open(t, dir)
+				}
+
+				db, err := NewDB(store)
+				if err != nil {
+					t.Fatalf("NewDB: %v", err)
+				}
+				return db
+			}
+
+			db := reopen()
+			id, err := db.NewBieter([]byte(`{"name":"hugo"}`), User{})
+			if err != nil {
+				t.Fatalf("NewBieter: %v", err)
+			}
+
+			if err := db.writeSnapshot(); err != nil {
+				t.Fatalf("writeSnapshot: %v", err)
+			}
+
+			if err := db.UpdateOffer(id, strings.NewReader(`{"offer":5000}`), 0, systemUser); err != nil {
+				t.Fatalf("UpdateOffer: %v", err)
+			}
+
+			db = reopen()
+			if db.snapshotAt != 1 || db.events != 2 {
+				t.Errorf("got snapshotAt=%d events=%d, expected 1 and 2", db.snapshotAt, db.events)
+			}
+
+			if got := db.Offer(id); got != 5000 {
+				t.Errorf("offer is %d, expected 5000", got)
+			}
+
+			for i := 0; i < 3; i++ {
+				if err := db.UpdateOffer(id, strings.NewReader(`{"offer":6000}`), 0, systemUser); err != nil {
+					t.Fatalf("UpdateOffer: %v", err)
+				}
+			}
+
+			// Deleted ids are not used again after the compaction.
+			if _, err := db.AddSchedule(strings.NewReader(`{"state":3,"at":"2099-01-01T00:00:00Z"}`), systemUser); err != nil {
+				t.Fatalf("AddSchedule: %v", err)
+			}
+			if err := db.DeleteSchedule(1, systemUser); err != nil {
+				t.Fatalf("DeleteSchedule: %v", err)
+			}
+			if _, err := db.AddVerteilstelle(strings.NewReader(`{"name":"Villingen"}`), systemUser); err != nil {
+				t.Fatalf("AddVerteilstelle: %v", err)
+			}
+			if err := db.DeleteVerteilstelle(1, systemUser); err != nil {
+				t.Fatalf("DeleteVerteilstelle: %v", err)
+			}
+
+			if _, err := db.Compact(); err != nil {
+				t.Fatalf("Compact: %v", err)
+			}
+
+			db = reopen()
+			defer db.Close()
+
+			if db.events != 3 {
+				t.Errorf("compacted store has %d events, expected 3", db.events)
+			}
+
+			if got := db.nextScheduleID(); got != 2 {
+				t.Errorf("next schedule id after compact is %d, expected 2", got)
+			}
+
+			if got := db.nextVerteilstelleID(); got != 2 {
+				t.Errorf("next verteilstelle id after compact is %d, expected 2", got)
+			}
+
+			if got := db.Offer(id); got != 6000 {
+				t.Errorf("offer after compact is %d, expected 6000", got)
+			}
+		})
+	}
+}
+
+func TestFileStoreTornLine(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		content string
+		expect  string
+	}{
+		{"complete", "{\"a\":1}\n{\"b\":2}\n", "{\"a\":1}\n{\"b\":2}\n"},
+		{"missing newline", "{\"a\":1}\n{\"b\":2}", "{\"a\":1}\n{\"b\":2}\n"},
+		{"torn", "{\"a\":1}\n{\"b\":", "{\"a\":1}\n"},
+		{"torn first line", "{\"b\":", ""},
+		{"long torn line", "{\"a\":1}\n{\"b\":\"" + strings.Repeat("x", 10000), "{\"a\":1}\n"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "db.jsonl")
+			if err := os.WriteFile(file, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("writing file: %v", err)
+			}
+
+			store, err := NewFileStore(file)
+			if err != nil {
+				t.Fatalf("NewFileStore: %v", err)
+			}
+			store.Close()
+
+			got, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("reading file: %v", err)
+			}
+
+			if string(got) != tt.expect {
+				t.Errorf("file is %q, expected %q", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestFileStoreConcurrentAppend(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "db.jsonl")
+	store, err := NewFileStore(file)
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	defer store.Close()
+
+	db, err := NewDB(store)
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := db.NewBieter([]byte(`{"name":"hugo"}`), User{}); err != nil {
+				t.Errorf("NewBieter: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var count int
+	if err := store.Iterate(func([]byte) error { count++; return nil }); err != nil {
+		t.Fatalf("Iterate: %v", err)
+	}
+
+	if count != 50 {
+		t.Errorf("store has %d events, expected 50", count)
+	}
+}
+
+func TestFileStoreConcurrentAppendError(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "db.jsonl")
+	store, err := NewFileStore(file)
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+
+	var waits []func() error
+	for i := 0; i < 20; i++ {
+		waits = append(waits, store.Append([]byte(`{}`)))
+	}
+
+	// Closing the file lets every sync and write fail.
+	store.Close()
+
+	var wg sync.WaitGroup
+	for _, wait := range waits {
+		wg.Add(2)
+		go func(wait func() error) {
+			defer wg.Done()
+			if err := wait(); err == nil {
+				t.Errorf("sync on closed file returned no error")
+			}
+		}(wait)
+		go func() {
+			defer wg.Done()
+			if err := store.Append([]byte(`{}`))(); err == nil {
+				t.Errorf("append on closed file returned no error")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestFileStoreLock(t *testing.T) {
+	dir := t.TempDir()
+	dbFile := filepath.Join(dir, "db.jsonl")
+	configFile := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(configFile, []byte("admin_password = \"secret\"\n"), 0600); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+
+	store, err := NewFileStore(dbFile)
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+
+	if _, err := NewFileStore(dbFile); !errors.Is(err, errDBLocked) {
+		t.Errorf("second NewFileStore returned %v, expected errDBLocked", err)
+	}
+
+	if err := Compact(configFile, dbFile); !errors.Is(err, errDBLocked) {
+		t.Errorf("Compact returned %v, expected errDBLocked", err)
+	}
+
+	if err := Rollover(configFile, dbFile, rolloverAll, nil, false); !errors.Is(err, errDBLocked) {
+		t.Errorf("Rollover returned %v, expected errDBLocked", err)
+	}
+
+	// After replacing the file, the new file has to be locked.
+	if _, err := store.Replace([][]byte{[]byte(`{}`)}); err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+
+	if _, err := NewFileStore(dbFile); !errors.Is(err, errDBLocked) {
+		t.Errorf("NewFileStore after Replace returned %v, expected errDBLocked", err)
+	}
+
+	store.Close()
+
+	other, err := NewFileStore(dbFile)
+	if err != nil {
+		t.Fatalf("NewFileStore after Close: %v", err)
+	}
+	other.Close()
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+)
+
+// tokenBytes is the number of random bytes of an access token.
+const tokenBytes = 24
+
+// newToken returns a random access token for a bieter.
+func newToken() (string, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("reading random bytes: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Token returns the access token of a bieter. It is empty, if the bieter has
+// no token.
+func (db *Database) Token(id string) string {
+	db.RLock()
+	defer db.RUnlock()
+
+	return db.bieterToken[id]
+}
+
+// FindBieter returns the id of the bieter with the access token key.
+//
+// A user with the permission p can also use the id of a bieter as key, if the
+// user can see the bieter.
+func (db *Database) FindBieter(key string, user User, p Permission) (string, bool) {
+	db.RLock()
+	defer db.RUnlock()
+
+	if id, ok := db.tokens[key]; ok {
+		return id, true
+	}
+
+	payload, ok := db.bieter[key]
+	if ok && user.can(p) && user.seesBieter(payload) {
+		return key, true
+	}
+	return "", false
+}
+
+// RotateToken gives a bieter a new access token. The old token becomes
+// invalid.
+func (db *Database) RotateToken(id string, user User) (string, error) {
+	if !user.can(permWrite) {
+		return "", errForbidden
+	}
+
+	event, err := newEventToken(id)
+	if err != nil {
+		return "", fmt.Errorf("creating token event: %w", err)
+	}
+
+	if err := db.writeEvent(event, user); err != nil {
+		return "", fmt.Errorf("writing token event: %w", err)
+	}
+	return event.Token, nil
+}
+
+// IssueTokens gives all bieters without an access token a new token. It
+// returns the number of new tokens.
+//
+// Bieters, that were created before there were tokens, have none.
+func (db *Database) IssueTokens(user User) (int, error) {
+	if !user.can(permWrite) {
+		return 0, errForbidden
+	}
+
+	db.RLock()
+	var ids []string
+	for id := range db.bieter {
+		if db.bieterToken[id] == "" {
+			ids = append(ids, id)
+		}
+	}
+	db.RUnlock()
+
+	for i, id := range ids {
+		if _, err := db.RotateToken(id, user); err != nil {
+			return i, fmt.Errorf("bieter %s: %w", id, err)
+		}
+	}
+	return len(ids), nil
+}
+
+// setToken sets the access token of a bieter and removes its old token.
+//
+// Has to be called with the write lock.
+func (db *Database) setToken(id, token string) {
+	if old, ok := db.bieterToken[id]; ok {
+		delete(db.tokens, old)
+	}
+	db.bieterToken[id] = token
+	db.tokens[token] = id
+}
+
+// removeToken removes the access token of a bieter.
+//
+// Has to be called with the write lock.
+func (db *Database) removeToken(id string) {
+	if old, ok := db.bieterToken[id]; ok {
+		delete(db.tokens, old)
+		delete(db.bieterToken, id)
+	}
+}
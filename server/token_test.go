@@ -0,0 +1,63 @@
+package server
+
+import "testing"
+
+func TestToken(t *testing.T) {
+	db, err := NewDB(NewMemoryStore(
+		`{"type":"update","payload":{"id":"1","payload":{"name":"Alt"}}}`,
+	))
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+
+	id, err := db.NewBieter([]byte(`{"name":"Hugo"}`), User{})
+	if err != nil {
+		t.Fatalf("NewBieter: %v", err)
+	}
+
+	token := db.Token(id)
+	if len(token) != 32 {
+		t.Fatalf("got token %q, expected 32 characters", token)
+	}
+
+	if _, ok := db.FindBieter(id, User{}, permRead); ok {
+		t.Errorf("found bieter by id without admin")
+	}
+	if got, ok := db.FindBieter(token, User{}, permRead); !ok || got != id {
+		t.Errorf("FindBieter(token) = %q, %t, expected %q", got, ok, id)
+	}
+	if got, ok := db.FindBieter(id, systemUser, permRead); !ok || got != id {
+		t.Errorf("FindBieter(id) as admin = %q, %t, expected %q", got, ok, id)
+	}
+
+	if _, err := db.RotateToken(id, User{}); err == nil {
+		t.Errorf("rotated token without admin")
+	}
+
+	newToken, err := db.RotateToken(id, systemUser)
+	if err != nil {
+		t.Fatalf("RotateToken: %v", err)
+	}
+	if _, ok := db.FindBieter(token, User{}, permRead); ok {
+		t.Errorf("old token is still valid")
+	}
+	if got, _ := db.FindBieter(newToken, User{}, permRead); got != id {
+		t.Errorf("new token is not valid")
+	}
+
+	count, err := db.IssueTokens(systemUser)
+	if err != nil {
+		t.Fatalf("IssueTokens: %v", err)
+	}
+	if count != 1 || db.Token("1") == "" {
+		t.Errorf("IssueTokens created %d tokens, expected 1 for the old bieter", count)
+	}
+
+	compacted := emptyDatabase()
+	for _, e := range db.compactEvents() {
+		e.execute(compacted)
+	}
+	if got, _ := compacted.FindBieter(newToken, User{}, permRead); got != id {
+		t.Errorf("token got lost by compaction")
+	}
+}
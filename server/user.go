@@ -0,0 +1,113 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Role is the role of an admin account.
+type Role string
+
+// Possible roles.
+const (
+	// roleBoard can do everything.
+	roleBoard Role = "board"
+
+	// roleTreasurer can read everything and download the exports.
+	roleTreasurer Role = "treasurer"
+
+	// roleCoordinator can read the bieters of one verteilstelle.
+	roleCoordinator Role = "coordinator"
+)
+
+// Permission is something a role is allowed to do.
+type Permission int
+
+// Possible permissions.
+const (
+	// permRead allows to read the bieters and the numbers of the bieterrunde.
+	permRead Permission = iota + 1
+
+	// permExport allows to download the contracts, the SEPA file and the
+	// export.
+	permExport
+
+	// permWrite allows to change everything.
+	permWrite
+)
+
+var rolePermissions = map[Role][]Permission{
+	roleBoard:       {permRead, permExport, permWrite},
+	roleTreasurer:   {permRead, permExport},
+	roleCoordinator: {permRead},
+}
+
+// UserConfig is an admin account in the config.
+type UserConfig struct {
+	Name         string `toml:"name"`
+	PasswordHash string `toml:"password_hash"`
+	Role         Role   `toml:"role"`
+
+	// Verteilstelle is the id of the verteilstelle of a coordinator.
+	Verteilstelle int `toml:"verteilstelle"`
+}
+
+func (u UserConfig) validate() error {
+	if u.Name == "" {
+		return fmt.Errorf("user without name")
+	}
+
+	if _, ok := rolePermissions[u.Role]; !ok {
+		return fmt.Errorf("user %s has unknown role %q", u.Name, u.Role)
+	}
+
+	if u.Role == roleCoordinator && u.Verteilstelle == 0 {
+		return fmt.Errorf("coordinator %s has no verteilstelle", u.Name)
+	}
+	return nil
+}
+
+// User is the person, that does a request or changes the database.
+//
+// The zero value is a bieter or anonymous visitor without any permission.
+type User struct {
+	Name          string
+	Role          Role
+	Verteilstelle int
+
+	// IP and RequestID are from the http request of the user. They are
+	// saved with the events.
+	IP        string
+	RequestID string
+}
+
+// systemUser is used for changes, that are not done by a person, like
+// commands.
+var systemUser = User{Name: "system", Role: roleBoard}
+
+// schedulerUser is used for the state changes of the schedule.
+var schedulerUser = User{Name: "scheduler", Role: roleBoard}
+
+// can returns true, if the user has the permission.
+func (u User) can(p Permission) bool {
+	for _, perm := range rolePermissions[u.Role] {
+		if perm == p {
+			return true
+		}
+	}
+	return false
+}
+
+// seesVerteilstelle returns true, if the user can read the bieters of a
+// verteilstelle. Only a coordinator is limited to one verteilstelle.
+func (u User) seesVerteilstelle(id int) bool {
+	if !u.can(permRead) {
+		return false
+	}
+	return u.Role != roleCoordinator || u.Verteilstelle == id
+}
+
+// seesBieter is like seesVerteilstelle for the verteilstelle of a payload.
+func (u User) seesBieter(payload json.RawMessage) bool {
+	return u.seesVerteilstelle(payloadVerteilstelle(payload))
+}
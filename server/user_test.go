@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestUserPermissions(t *testing.T) {
+	treasurer := User{Name: "kasse", Role: roleTreasurer}
+	coordinator := User{Name: "villingen", Role: roleCoordinator, Verteilstelle: 1}
+
+	for _, tt := range []struct {
+		name   string
+		user   User
+		perm   Permission
+		expect bool
+	}{
+		{"board write", User{Role: roleBoard}, permWrite, true},
+		{"treasurer export", treasurer, permExport, true},
+		{"treasurer write", treasurer, permWrite, false},
+		{"coordinator read", coordinator, permRead, true},
+		{"coordinator export", coordinator, permExport, false},
+		{"bieter read", User{}, permRead, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.can(tt.perm); got != tt.expect {
+				t.Errorf("can() = %t, expected %t", got, tt.expect)
+			}
+		})
+	}
+
+	if !coordinator.seesVerteilstelle(1) || coordinator.seesVerteilstelle(2) {
+		t.Errorf("coordinator sees the wrong verteilstellen")
+	}
+	if !treasurer.seesVerteilstelle(2) {
+		t.Errorf("treasurer does not see all verteilstellen")
+	}
+}
+
+func TestEventUser(t *testing.T) {
+	store := NewMemoryStore(
+		`{"type":"verteilstelle","payload":{"id":1,"name":"Villingen"}}`,
+		`{"type":"update","payload":{"id":"1","payload":{"name":"Hugo","verteilstelle":1}}}`,
+	)
+	db, err := NewDB(store)
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+
+	if _, ok := db.FindBieter("1", User{Role: roleCoordinator, Verteilstelle: 2}, permRead); ok {
+		t.Errorf("coordinator found a bieter of another verteilstelle")
+	}
+	if _, ok := db.FindBieter("1", User{Role: roleCoordinator, Verteilstelle: 1}, permRead); !ok {
+		t.Errorf("coordinator did not find a bieter of the own verteilstelle")
+	}
+
+	board := User{Name: "erika", Role: roleBoard}
+	if err := db.DeleteBieter("1", board); err != nil {
+		t.Fatalf("DeleteBieter: %v", err)
+	}
+
+	var last string
+	store.Iterate(func(record []byte) error {
+		last = string(record)
+		return nil
+	})
+
+	if !strings.Contains(last, `"user":"erika"`) {
+		t.Errorf("event %s does not contain the user", last)
+	}
+}
+
+func TestTokenOnlyForWrite(t *testing.T) {
+	db, err := NewDB(NewMemoryStore(
+		`{"type":"update","payload":{"id":"1","payload":{"name":"Hugo"},"token":"secret-token"}}`,
+	))
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+
+	router := mux.NewRouter()
+	handleBieterList(router, db, DefaultConfig())
+	handleBieter(router, db, DefaultConfig(), newFailureLimiter(DefaultConfig().RateLimit), nil, nil)
+
+	request := func(path string, user User) string {
+		r := httptest.NewRequest("GET", path, nil)
+		r = r.WithContext(context.WithValue(r.Context(), userKey, user))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		if w.Code != 200 {
+			t.Fatalf("GET %s returned %d: %s", path, w.Code, w.Body)
+		}
+		return w.Body.String()
+	}
+
+	treasurer := User{Name: "kasse", Role: roleTreasurer}
+	for _, path := range []string{"/api/bieter", "/api/bieter/1"} {
+		if body := request(path, treasurer); strings.Contains(body, "secret-token") {
+			t.Errorf("treasurer got the token from %s: %s", path, body)
+		}
+	}
+
+	if body := request("/api/bieter", User{Role: roleBoard}); !strings.Contains(body, "secret-token") {
+		t.Errorf("board did not get the token: %s", body)
+	}
+
+	if body := request("/api/bieter/secret-token", User{}); !strings.Contains(body, "secret-token") {
+		t.Errorf("bieter did not get the own token: %s", body)
+	}
+}
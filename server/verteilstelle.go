@@ -0,0 +1,218 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Verteilstelle is a location, where the members pick up their shares.
+type Verteilstelle struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Address string `json:"address"`
+
+	// Pickup is the day and time of the pickup like "Donnerstag 16-19 Uhr".
+	Pickup string `json:"pickup"`
+
+	// Capacity is the maximum number of shares. 0 means unlimited.
+	Capacity int `json:"capacity"`
+}
+
+func (v Verteilstelle) String() string {
+	return v.Name
+}
+
+// VerteilstelleCount are the numbers of one verteilstelle.
+type VerteilstelleCount struct {
+	Verteilstelle
+
+	// Members is the number of bieters, that chose the verteilstelle.
+	Members int `json:"members"`
+
+	// Offers is the number of these bieters with an offer and OfferSum the
+	// sum of their monthly offers in cent.
+	Offers   int `json:"offers"`
+	OfferSum int `json:"offer_sum"`
+}
+
+// Full returns true, if no more members can choose the verteilstelle.
+func (c VerteilstelleCount) Full() bool {
+	return c.Capacity > 0 && c.Members >= c.Capacity
+}
+
+// Verteilstelle returns one verteilstelle.
+func (db *Database) Verteilstelle(id int) (Verteilstelle, bool) {
+	db.RLock()
+	defer db.RUnlock()
+
+	v, ok := db.verteilstellen[id]
+	return v, ok
+}
+
+// Verteilstellen returns all verteilstellen with their numbers sorted by id.
+func (db *Database) Verteilstellen() []VerteilstelleCount {
+	db.RLock()
+	defer db.RUnlock()
+
+	list := make([]VerteilstelleCount, 0, len(db.verteilstellen))
+	index := make(map[int]int, len(db.verteilstellen))
+	for i, v := range db.sortedVerteilstellen() {
+		list = append(list, VerteilstelleCount{Verteilstelle: v})
+		index[v.ID] = i
+	}
+
+	for id, payload := range db.bieter {
+		i, ok := index[payloadVerteilstelle(payload)]
+		if !ok {
+			continue
+		}
+
+		list[i].Members++
+		if offer := db.offer[id]; offer > 0 {
+			list[i].Offers++
+			list[i].OfferSum += offer
+		}
+	}
+
+	return list
+}
+
+func (db *Database) sortedVerteilstellen() []Verteilstelle {
+	list := make([]Verteilstelle, 0, len(db.verteilstellen))
+	for _, v := range db.verteilstellen {
+		list = append(list, v)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// verteilstelleMembers returns the number of bieters of a verteilstelle.
+//
+// Has to be called with the lock.
+func (db *Database) verteilstelleMembers(id int) int {
+	var members int
+	for _, payload := range db.bieter {
+		if payloadVerteilstelle(payload) == id {
+			members++
+		}
+	}
+	return members
+}
+
+// payloadVerteilstelle returns the verteilstelle of a saved payload. Returns 0,
+// if the payload can not be decoded.
+func payloadVerteilstelle(payload json.RawMessage) int {
+	var data struct {
+		Verteilstelle int `json:"verteilstelle"`
+	}
+	json.Unmarshal(payload, &data)
+	return data.Verteilstelle
+}
+
+// AddVerteilstelle creates a new verteilstelle. It is read from r and returned.
+func (db *Database) AddVerteilstelle(r io.Reader, user User) (Verteilstelle, error) {
+	if !user.can(permWrite) {
+		return Verteilstelle{}, errForbidden
+	}
+
+	var v Verteilstelle
+	if err := json.NewDecoder(r).Decode(&v); err != nil {
+		return Verteilstelle{}, fmt.Errorf("decoding verteilstelle: %w", err)
+	}
+
+	for {
+		v.ID = db.nextVerteilstelleID()
+		event, err := newEventVerteilstelle(v, true)
+		if err != nil {
+			return Verteilstelle{}, fmt.Errorf("creating verteilstelle event: %w", err)
+		}
+
+		if err := db.writeEvent(event, user); err != nil {
+			if errors.Is(err, errVerteilstelleIDExists) {
+				continue
+			}
+			return Verteilstelle{}, fmt.Errorf("writing verteilstelle event: %w", err)
+		}
+		return v, nil
+	}
+}
+
+// defaultVerteilstellen are the verteilstellen, that the client had built in,
+// before they could be created with the api. Old bieters use their ids.
+var defaultVerteilstellen = []Verteilstelle{
+	{ID: 1, Name: "Villingen"},
+	{ID: 2, Name: "Schwenningen"},
+	{ID: 3, Name: "Überauchen"},
+}
+
+// seedVerteilstellen creates the default verteilstellen, if no verteilstelle
+// was ever created.
+func (db *Database) seedVerteilstellen() error {
+	db.RLock()
+	seeded := db.lastVerteilstelleID > 0
+	db.RUnlock()
+
+	if seeded {
+		return nil
+	}
+
+	for _, v := range defaultVerteilstellen {
+		event, err := newEventVerteilstelle(v, true)
+		if err != nil {
+			return fmt.Errorf("creating verteilstelle event: %w", err)
+		}
+
+		if err := db.writeEvent(event, systemUser); err != nil {
+			return fmt.Errorf("writing verteilstelle event: %w", err)
+		}
+	}
+	return nil
+}
+
+func (db *Database) nextVerteilstelleID() int {
+	db.RLock()
+	defer db.RUnlock()
+
+	return db.lastVerteilstelleID + 1
+}
+
+// UpdateVerteilstelle changes a verteilstelle. The new values are read from r.
+func (db *Database) UpdateVerteilstelle(id int, r io.Reader, user User) (Verteilstelle, error) {
+	if !user.can(permWrite) {
+		return Verteilstelle{}, errForbidden
+	}
+
+	var v Verteilstelle
+	if err := json.NewDecoder(r).Decode(&v); err != nil {
+		return Verteilstelle{}, fmt.Errorf("decoding verteilstelle: %w", err)
+	}
+	v.ID = id
+
+	event, err := newEventVerteilstelle(v, false)
+	if err != nil {
+		return Verteilstelle{}, fmt.Errorf("creating verteilstelle event: %w", err)
+	}
+
+	if err := db.writeEvent(event, user); err != nil {
+		return Verteilstelle{}, fmt.Errorf("writing verteilstelle event: %w", err)
+	}
+	return v, nil
+}
+
+// DeleteVerteilstelle removes a verteilstelle. It is only possible, if no
+// bieter has chosen it.
+func (db *Database) DeleteVerteilstelle(id int, user User) error {
+	if !user.can(permWrite) {
+		return errForbidden
+	}
+
+	if err := db.writeEvent(newEventVerteilstelleDelete(id), user); err != nil {
+		return fmt.Errorf("writing verteilstelle delete event: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerteilstelleCapacity(t *testing.T) {
+	db, err := NewDB(NewMemoryStore(
+		`{"type":"verteilstelle","payload":{"id":1,"name":"Villingen","capacity":1}}`,
+		`{"type":"verteilstelle","payload":{"id":2,"name":"Schwenningen"}}`,
+		`{"type":"update","payload":{"id":"1","payload":{"name":"Hugo","verteilstelle":1}}}`,
+		`{"type":"offer","payload":{"id":"1","offer":5000}}`,
+	))
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+
+	if _, err := db.NewBieter([]byte(`{"name":"Erika","verteilstelle":1}`), User{}); err == nil || !strings.Contains(err.Error(), "voll") {
+		t.Errorf("got error %v, expected that the verteilstelle is full", err)
+	}
+
+	if _, err := db.NewBieter([]byte(`{"name":"Erika","verteilstelle":3}`), User{}); err == nil {
+		t.Errorf("got no error for an unknown verteilstelle")
+	}
+
+	if _, err := db.UpdateBieter("1", strings.NewReader(`{"name":"Hugo Müller","verteilstelle":1}`), User{}); err != nil {
+		t.Errorf("updating a bieter of a full verteilstelle: %v", err)
+	}
+
+	if _, err := db.NewBieter([]byte(`{"name":"Erika","verteilstelle":1}`), systemUser); err != nil {
+		t.Errorf("admin can not exceed the capacity: %v", err)
+	}
+
+	if err := db.DeleteVerteilstelle(1, systemUser); err == nil {
+		t.Errorf("got no error when deleting a verteilstelle with members")
+	}
+
+	counts := db.Verteilstellen()
+	if len(counts) != 2 {
+		t.Fatalf("got %d verteilstellen, expected 2", len(counts))
+	}
+
+	if c := counts[0]; c.Members != 2 || c.Offers != 1 || c.OfferSum != 5000 || !c.Full() {
+		t.Errorf("got %+v for Villingen, expected 2 members, 1 offer with 5000 and full", c)
+	}
+
+	if c := counts[1]; c.Members != 0 || c.Full() {
+		t.Errorf("got %+v for Schwenningen, expected 0 members and not full", c)
+	}
+
+	v, err := db.AddVerteilstelle(strings.NewReader(`{"name":"Acker","pickup":"Samstag 10-12 Uhr"}`), systemUser)
+	if err != nil {
+		t.Fatalf("AddVerteilstelle: %v", err)
+	}
+	if v.ID != 3 {
+		t.Errorf("new verteilstelle has id %d, expected 3", v.ID)
+	}
+}
+
+func TestSeedVerteilstellen(t *testing.T) {
+	// A database from the time, when the client had the verteilstellen
+	// built in.
+	db, err := NewDB(NewMemoryStore(
+		`{"type":"update","payload":{"id":"1","payload":{"name":"Hugo","verteilstelle":3}}}`,
+	))
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+
+	if err := db.seedVerteilstellen(); err != nil {
+		t.Fatalf("seedVerteilstellen: %v", err)
+	}
+
+	counts := db.Verteilstellen()
+	if len(counts) != 3 {
+		t.Fatalf("got %d verteilstellen, expected 3", len(counts))
+	}
+	if counts[2].Name != "Überauchen" || counts[2].Members != 1 {
+		t.Errorf("got verteilstelle %+v, expected Überauchen with one member", counts[2])
+	}
+
+	if err := db.DeleteVerteilstelle(1, systemUser); err != nil {
+		t.Fatalf("DeleteVerteilstelle: %v", err)
+	}
+
+	if err := db.seedVerteilstellen(); err != nil {
+		t.Fatalf("seedVerteilstellen: %v", err)
+	}
+
+	if _, ok := db.Verteilstelle(1); ok {
+		t.Errorf("deleted verteilstelle was created again")
+	}
+}
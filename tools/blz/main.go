@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Converts the bank code file of the Deutsche Bundesbank to the file
+// server/blz.csv.
+//
+// The file is the "Bankleitzahlendatei" in the text format (blz_YYYYMMDD.txt),
+// that is published at
+// https://www.bundesbank.de/de/aufgaben/unbarer-zahlungsverkehr/serviceangebot/bankleitzahlen/download-bankleitzahlen-602592
+//
+//	go run ./tools/blz blz_20240304.txt > server/blz.csv
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "Usage: blz BANKLEITZAHLENDATEI.txt")
+		os.Exit(2)
+	}
+
+	f, err := os.Open(os.Args[1])
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	defer f.Close()
+
+	banks, err := readBundesbank(f)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	fmt.Fprintf(w, "# Bankleitzahlendatei der Deutschen Bundesbank (%s).\n", filepath.Base(os.Args[1]))
+	fmt.Fprintln(w, "# Erzeugt mit: go run ./tools/blz DATEI > server/blz.csv")
+	fmt.Fprintln(w, "# Format: Bankleitzahl;BIC;Name")
+	for _, b := range banks {
+		fmt.Fprintf(w, "%s;%s;%s\n", b.blz, b.bic, b.name)
+	}
+
+	if err := w.Flush(); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+}
+
+type bank struct {
+	blz  string
+	bic  string
+	name string
+}
+
+// readBundesbank reads the records of the bank code file. Each line has a
+// fixed length of 174 characters in ISO-8859-1.
+//
+// Only the main record of each bank code (Merkmal 1) is used, because the
+// branches have the same BIC or none. Deleted bank codes are skipped.
+func readBundesbank(r io.Reader) ([]bank, error) {
+	var banks []bank
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		record := scanner.Bytes()
+		if strings.TrimSpace(string(record)) == "" {
+			continue
+		}
+
+		if len(record) < 160 {
+			return nil, fmt.Errorf("line %d: record has %d characters, expected 174", line, len(record))
+		}
+
+		field := func(from, to int) string {
+			return strings.TrimSpace(latin1(record[from-1 : to]))
+		}
+
+		if field(9, 9) != "1" || field(159, 159) == "D" {
+			continue
+		}
+
+		bic := field(140, 150)
+		if bic == "" {
+			continue
+		}
+
+		banks = append(banks, bank{
+			blz:  field(1, 8),
+			bic:  bic,
+			name: strings.ReplaceAll(field(10, 67), ";", ","),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+
+	sort.Slice(banks, func(i, j int) bool { return banks[i].blz < banks[j].blz })
+	return banks, nil
+}
+
+// latin1 decodes ISO-8859-1, where each byte is one character.
+func latin1(bs []byte) string {
+	runes := make([]rune, len(bs))
+	for i, b := range bs {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
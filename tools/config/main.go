@@ -1,18 +1,57 @@
 package main
 
 import (
+	"bufio"
+	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/ostcar/bieterrunde/server"
 	"github.com/pelletier/go-toml/v2"
 )
 
+// Without arguments, the default config with the admin password "admin" is
+// printed. With the argument "hash", a password is read from stdin and the
+// line for the config is printed.
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "hash" {
+		if err := printHash(); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+
+	hash, err := server.HashPassword("admin")
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
 	c := server.DefaultConfig()
-	c.AdminPW = "admin"
+	c.AdminPWHash = hash
 
 	if err := toml.NewEncoder(os.Stdout).Encode(c); err != nil {
 		log.Fatalf("Error encoding config: %v", err)
 	}
 }
+
+func printHash() error {
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		return fmt.Errorf("reading password: %w", err)
+	}
+
+	password = strings.TrimRight(password, "\r\n")
+	if password == "" {
+		return fmt.Errorf("empty password")
+	}
+
+	hash, err := server.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("admin_password_hash = '%s'\n", hash)
+	return nil
+}